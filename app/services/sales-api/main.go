@@ -6,6 +6,7 @@ import (
 	"expvar"
 	"fmt"
 	"net/http"
+	stdmail "net/mail"
 	"os"
 	"os/signal"
 	"runtime"
@@ -14,9 +15,17 @@ import (
 
 	"github.com/ardanlabs/conf/v3"
 	"github.com/islamghany/service/app/services/sales-api/v1/handlers"
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/core/user/stores/userdb"
+	"github.com/islamghany/service/business/data/dbmigrate"
+	database "github.com/islamghany/service/business/data/dbsql"
 	v1 "github.com/islamghany/service/business/web/v1"
+	"github.com/islamghany/service/business/web/v1/auth"
 	"github.com/islamghany/service/business/web/v1/debug"
+	"github.com/islamghany/service/foundation/health"
 	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/mail"
+	"github.com/islamghany/service/foundation/retry"
 	"github.com/islamghany/service/foundation/web"
 )
 
@@ -25,15 +34,9 @@ var build = "develop"
 func main() {
 	// ----------------------------------------------------------
 	// intialize the logger
-	loggerEvents := logger.Events{
-		Error: func(ctx context.Context, r logger.Record) {
-			fmt.Printf("ERROR: %s\n", r.Message)
-		},
-	}
-	tracerIDFn := func(ctx context.Context) string {
-		return web.GetTraceID(ctx)
-	}
-	log := logger.NewWithEvents(os.Stdout, logger.LevelInfo, "sales-api", tracerIDFn, loggerEvents)
+	// This logger reports startup and configuration problems until the
+	// configured logger is constructed in run.
+	log := logger.NewWithEvents(os.Stdout, logger.LevelInfo, "sales-api", traceIDFn, loggerEvents)
 	// -----------------------------------------------------------
 	ctx := context.Background()
 	if err := run(ctx, log); err != nil {
@@ -57,10 +60,76 @@ func run(ctx context.Context, log *logger.Logger) error {
 			WriteTimeout       time.Duration `conf:"default:10s"`
 			IdleTimeout        time.Duration `conf:"default:120s"`
 			ShutdownTimeout    time.Duration `conf:"default:20s,mask"`
+			RequestTimeout     time.Duration `conf:"default:9s"`
 			APIHost            string        `conf:"default:0.0.0.0:8000"`
 			DebugHost          string        `conf:"default:0.0.0.0:4000"`
 			CORSAllowedOrigins []string      `conf:"default:*"`
+			ClientIPHeader     string        `conf:"help:header with the client IP set by a trusted proxy such as X-Forwarded-For"`
+		}
+		Health struct {
+			CheckTimeout time.Duration `conf:"default:2s"`
+		}
+		Users struct {
+			PurgeInterval  time.Duration `conf:"default:1h"`
+			PurgeRetention time.Duration `conf:"default:720h"`
+			ResetURL       string        `conf:"default:http://localhost:3000/password/reset"`
+			VerifyURL      string        `conf:"default:http://localhost:3000/verify"`
+			ResetTokenTTL  time.Duration `conf:"default:1h"`
+			VerifyTokenTTL time.Duration `conf:"default:48h"`
+			AttemptsPerIP  int           `conf:"default:20"`
+			AttemptsWindow time.Duration `conf:"default:10m"`
 		}
+		Auth struct {
+			KeysFolder string        `conf:"default:zarf/keys/"`
+			ActiveKID  string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			Issuer     string        `conf:"default:service project"`
+			TokenTTL   time.Duration `conf:"default:1h"`
+		}
+		Passwords struct {
+			MinLength        int           `conf:"default:10"`
+			MaxLength        int           `conf:"default:72"`
+			MinClasses       int           `conf:"default:3"`
+			Algorithm        string        `conf:"default:bcrypt,help:bcrypt or argon2id"`
+			BcryptCost       int           `conf:"default:10"`
+			Argon2Time       uint32        `conf:"default:2"`
+			Argon2Memory     uint32        `conf:"default:19456"`
+			Argon2Threads    uint8         `conf:"default:1"`
+			LockoutThreshold int           `conf:"default:5"`
+			LockoutWindow    time.Duration `conf:"default:1m"`
+			LockoutMaxWindow time.Duration `conf:"default:24h"`
+		}
+		Mail struct {
+			Sender     string `conf:"default:file,help:file or smtp"`
+			Dir        string `conf:"default:zarf/mail/"`
+			From       string `conf:"default:no-reply@example.com"`
+			Host       string `conf:"default:localhost"`
+			Port       int    `conf:"default:587"`
+			User       string
+			Password   string `conf:"mask"`
+			DisableTLS bool   `conf:"default:false"`
+		}
+		DB struct {
+			User             string `conf:"default:postgres"`
+			Password         string `conf:"default:postgres,mask"`
+			Host             string `conf:"default:database-service.sales-system.svc.cluster.local"`
+			Name             string `conf:"default:postgres"`
+			Schema           string
+			MaxIdleConns     int           `conf:"default:2"`
+			MaxOpenConns     int           `conf:"default:0"`
+			ConnMaxLifetime  time.Duration `conf:"default:30m"`
+			ConnMaxIdleTime  time.Duration `conf:"default:5m"`
+			StatementTimeout time.Duration `conf:"default:30s"`
+			DisableTLS       bool          `conf:"default:true"`
+			ConnectTimeout   time.Duration `conf:"default:30s"`
+			AutoMigrate      bool          `conf:"default:false"`
+			Replicas         struct {
+				Hosts            []string
+				ReadYourWrites   time.Duration `conf:"default:2s"`
+				CheckInterval    time.Duration `conf:"default:5s"`
+				FailureThreshold int           `conf:"default:3"`
+			}
+		}
+		Log logConfig
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -78,6 +147,27 @@ func run(ctx context.Context, log *logger.Logger) error {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
+	// -------------------------------------------------------------------------
+	// Logging
+
+	log, ring, closeLog, err := newLogger(cfg.Log)
+	if err != nil {
+		return fmt.Errorf("constructing logger: %w", err)
+	}
+	defer closeLog()
+
+	// Make sure queued records are written before the file is closed and the
+	// program exits. Once closed the logger writes synchronously, so the
+	// error can still be logged.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := log.Close(ctx); err != nil {
+			log.Error(ctx, "shutdown", "msg", err)
+		}
+	}()
+
 	// -------------------------------------------------------------------------
 	// App Starting
 
@@ -92,13 +182,164 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	expvar.NewString("build").Set(build)
 
+	// -------------------------------------------------------------------------
+	// Password Support
+
+	pwCfg := user.PasswordConfig{
+		MinLength:        cfg.Passwords.MinLength,
+		MaxLength:        cfg.Passwords.MaxLength,
+		MinClasses:       cfg.Passwords.MinClasses,
+		Algorithm:        cfg.Passwords.Algorithm,
+		BcryptCost:       cfg.Passwords.BcryptCost,
+		Argon2Time:       cfg.Passwords.Argon2Time,
+		Argon2Memory:     cfg.Passwords.Argon2Memory,
+		Argon2Threads:    cfg.Passwords.Argon2Threads,
+		LockoutThreshold: cfg.Passwords.LockoutThreshold,
+		LockoutWindow:    cfg.Passwords.LockoutWindow,
+		LockoutMaxWindow: cfg.Passwords.LockoutMaxWindow,
+	}
+	if err := pwCfg.Validate(); err != nil {
+		return fmt.Errorf("passwords config: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Database Support
+
+	log.Info(ctx, "startup", "status", "initializing database support", "host", cfg.DB.Host)
+
+	db, err := database.OpenCluster(database.Config{
+		User:             cfg.DB.User,
+		Password:         cfg.DB.Password,
+		Host:             cfg.DB.Host,
+		Name:             cfg.DB.Name,
+		Schema:           cfg.DB.Schema,
+		MaxIdleConns:     cfg.DB.MaxIdleConns,
+		MaxOpenConns:     cfg.DB.MaxOpenConns,
+		ConnMaxLifetime:  cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.DB.ConnMaxIdleTime,
+		StatementTimeout: cfg.DB.StatementTimeout,
+		DisableTLS:       cfg.DB.DisableTLS,
+	}, database.ClusterConfig{
+		ReplicaHosts:     cfg.DB.Replicas.Hosts,
+		ReadYourWrites:   cfg.DB.Replicas.ReadYourWrites,
+		CheckInterval:    cfg.DB.Replicas.CheckInterval,
+		FailureThreshold: cfg.DB.Replicas.FailureThreshold,
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping database support", "host", cfg.DB.Host)
+		db.Close()
+	}()
+
+	// The database may still be starting, so keep trying for a while before
+	// giving up.
+	if err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.DB.ConnectTimeout)
+		defer cancel()
+
+		retryCfg := retry.Config{
+			Initial: 250 * time.Millisecond,
+			Max:     5 * time.Second,
+			OnRetry: func(attempt int, err error, wait time.Duration) {
+				log.Info(ctx, "startup", "status", "waiting for database", "attempt", attempt, "wait", wait.String(), "msg", err)
+			},
+		}
+
+		if err := retry.Do(ctx, retryCfg, func(ctx context.Context) error {
+			return database.Ping(ctx, db.Primary())
+		}); err != nil {
+			return fmt.Errorf("status check database: %w", err)
+		}
+
+		if cfg.DB.AutoMigrate {
+			log.Info(ctx, "startup", "status", "migrating database")
+			if err := dbmigrate.Migrate(ctx, db.Primary()); err != nil {
+				return fmt.Errorf("migrating database: %w", err)
+			}
+		}
+
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	expvar.Publish("db", expvar.Func(func() any { return db.Stats() }))
+
+	// -------------------------------------------------------------------------
+	// Purge Deleted Users
+
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	purgeDone := make(chan struct{})
+
+	go func() {
+		defer close(purgeDone)
+		purgeUsers(purgeCtx, log, user.NewCore(log, userdb.NewStore(log, db), pwCfg), cfg.Users.PurgeInterval, cfg.Users.PurgeRetention)
+	}()
+
+	// Stop purging before the database is closed.
+	defer func() {
+		stopPurge()
+		<-purgeDone
+	}()
+
+	// -------------------------------------------------------------------------
+	// Auth Support
+
+	log.Info(ctx, "startup", "status", "initializing authentication support", "kid", cfg.Auth.ActiveKID)
+
+	ath, err := auth.New(auth.Config{
+		KeysFolder: cfg.Auth.KeysFolder,
+		ActiveKID:  cfg.Auth.ActiveKID,
+		Issuer:     cfg.Auth.Issuer,
+		TTL:        cfg.Auth.TokenTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing auth: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Mail Support
+
+	log.Info(ctx, "startup", "status", "initializing mail support", "sender", cfg.Mail.Sender)
+
+	mailer, err := newMailer(cfg.Mail.Sender, cfg.Mail.Dir, cfg.Mail.From, mail.SMTPConfig{
+		Host:       cfg.Mail.Host,
+		Port:       cfg.Mail.Port,
+		Username:   cfg.Mail.User,
+		Password:   cfg.Mail.Password,
+		DisableTLS: cfg.Mail.DisableTLS,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing mailer: %w", err)
+	}
+
+	// Emails are sent in the background so requests never wait for the mail
+	// server. The queued ones are sent before the program exits.
+	mailQueue := mail.NewQueue(mailer, mail.QueueConfig{
+		OnError: func(msg mail.Message, err error) {
+			log.Error(ctx, "send email", "subject", msg.Subject, "msg", err)
+		},
+	})
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+		defer cancel()
+
+		if err := mailQueue.Close(ctx); err != nil {
+			log.Error(ctx, "shutdown", "msg", err)
+		}
+	}()
+
 	// -------------------------------------------------------------------------
 	// Start Debug Service
 
 	go func() {
 		log.Info(ctx, "startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
 
-		if err := http.ListenAndServe(cfg.Web.DebugHost, debug.Mux()); err != nil {
+		if err := http.ListenAndServe(cfg.Web.DebugHost, debug.Mux(debug.Config{Log: log, Logs: ring, DB: db})); err != nil {
 			log.Error(ctx, "shutdown", "status", "debug v1 router closed", "host", cfg.Web.DebugHost, "msg", err)
 		}
 	}()
@@ -111,10 +352,51 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// signal.Notify() and will retain their default behavior.
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	// Construct the checker the readiness endpoint runs. Dependencies register
+	// their checks here as they are initialized.
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	checker.Register(health.Check{
+		Name:     "postgres",
+		Critical: true,
+		Retry: retry.Config{
+			MaxAttempts: 3,
+			Initial:     50 * time.Millisecond,
+			Max:         250 * time.Millisecond,
+		},
+		Fn: func(ctx context.Context) error {
+			return database.Ping(ctx, db.Primary())
+		},
+	})
+
+	// Reads fall back to the primary when replicas are ejected, so they
+	// don't decide readiness.
+	if len(cfg.DB.Replicas.Hosts) > 0 {
+		checker.Register(health.Check{
+			Name: "postgres-replicas",
+			Fn:   db.ReplicaCheck,
+		})
+	}
+
 	cfgMux := v1.APIMuxConfig{
-		Build:    build,
-		Shutdown: shutdown,
-		Log:      log,
+		Build:     build,
+		Shutdown:  shutdown,
+		Log:       log,
+		Health:    checker,
+		DB:        db,
+		Auth:      ath,
+		Mailer:    mailQueue,
+		Passwords: pwCfg,
+		Accounts: v1.AccountsConfig{
+			ResetURL:       cfg.Users.ResetURL,
+			VerifyURL:      cfg.Users.VerifyURL,
+			ResetTokenTTL:  cfg.Users.ResetTokenTTL,
+			VerifyTokenTTL: cfg.Users.VerifyTokenTTL,
+			AttemptsPerIP:  cfg.Users.AttemptsPerIP,
+			AttemptsWindow: cfg.Users.AttemptsWindow,
+			ClientIPHeader: cfg.Web.ClientIPHeader,
+		},
+
+		RequestTimeout: cfg.Web.RequestTimeout,
 	}
 	apiMux := v1.APIMux(cfgMux, handlers.Routes{})
 
@@ -146,6 +428,10 @@ func run(ctx context.Context, log *logger.Logger) error {
 		log.Info(ctx, "shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Info(ctx, "shutdown", "status", "shutdown complete", "signal", sig)
 
+		// Report not ready right away so no new traffic is routed to this
+		// instance while in-flight requests drain.
+		checker.Shutdown()
+
 		ctx, cancel := context.WithTimeout(ctx, cfg.Web.ShutdownTimeout)
 		defer cancel()
 		// Call Shutdown() on our server, passing in the context we just made.
@@ -162,3 +448,221 @@ func run(ctx context.Context, log *logger.Logger) error {
 	return nil
 
 }
+
+// =============================================================================
+
+// loggerEvents contains the functions executed when records are logged at
+// specific levels.
+var loggerEvents = logger.Events{
+	Error: func(ctx context.Context, r logger.Record) {
+		fmt.Printf("ERROR: %s\n", r.Message)
+	},
+}
+
+// traceIDFn returns the trace ID of the request being handled.
+func traceIDFn(ctx context.Context) string {
+	return web.GetTraceID(ctx)
+}
+
+// logConfig is the configuration for the logger.
+type logConfig struct {
+	Level  string `conf:"default:INFO"`
+	Format string `conf:"default:json"`
+	File   struct {
+		Path        string
+		Level       string        `conf:"default:DEBUG"`
+		Format      string        `conf:"default:json"`
+		MaxSizeMB   int64         `conf:"default:100"`
+		RotateEvery time.Duration `conf:"default:24h"`
+		MaxBackups  int           `conf:"default:7"`
+		MaxAge      time.Duration `conf:"default:168h"`
+		Compress    bool          `conf:"default:true"`
+	}
+	Redact struct {
+		Enabled bool     `conf:"default:true"`
+		Keys    []string `conf:"default:password;token;authorization;email;secret;cookie"`
+		Mode    string   `conf:"default:mask"`
+	}
+	Sample struct {
+		Enabled    bool          `conf:"default:false"`
+		Interval   time.Duration `conf:"default:1s"`
+		First      int           `conf:"default:100"`
+		Thereafter int           `conf:"default:100"`
+	}
+	Ring struct {
+		Size int `conf:"default:1000"`
+	}
+	Async struct {
+		Enabled   bool   `conf:"default:false"`
+		QueueSize int    `conf:"default:4096"`
+		Overflow  string `conf:"default:block"`
+	}
+}
+
+// newLogger constructs the logger based on the configuration. Records are
+// always written to stdout and, when a path is provided, to a rotating file
+// with its own format and level. When a ring size is provided, the most recent
+// records are also kept in the returned ring buffer. The returned function
+// closes the file.
+func newLogger(cfg logConfig) (*logger.Logger, *logger.RingBuffer, func() error, error) {
+	minLevel, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing level: %w", err)
+	}
+
+	stdoutFormat, err := logger.ParseFormat(cfg.Format)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sinks := []logger.Sink{
+		{Writer: os.Stdout, Format: stdoutFormat, MinLevel: minLevel},
+	}
+
+	closeFn := func() error { return nil }
+
+	if file := cfg.File; file.Path != "" {
+		fileLevel, err := logger.ParseLevel(file.Level)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parsing file level: %w", err)
+		}
+
+		fileFormat, err := logger.ParseFormat(file.Format)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		fw, err := logger.NewFileWriter(logger.FileConfig{
+			Path:        file.Path,
+			MaxSize:     file.MaxSizeMB << 20,
+			RotateEvery: file.RotateEvery,
+			MaxBackups:  file.MaxBackups,
+			MaxAge:      file.MaxAge,
+			Compress:    file.Compress,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		sinks = append(sinks, logger.Sink{Writer: fw, Format: fileFormat, MinLevel: fileLevel})
+		closeFn = fw.Close
+	}
+
+	var redact *logger.RedactConfig
+	if cfg.Redact.Enabled {
+		mode, err := logger.ParseRedactMode(cfg.Redact.Mode)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		redact = &logger.RedactConfig{
+			Keys: cfg.Redact.Keys,
+			Mode: mode,
+		}
+	}
+
+	var sample *logger.SampleConfig
+	if cfg.Sample.Enabled {
+		sample = &logger.SampleConfig{
+			Interval:   cfg.Sample.Interval,
+			First:      cfg.Sample.First,
+			Thereafter: cfg.Sample.Thereafter,
+		}
+	}
+
+	var async *logger.AsyncConfig
+	if cfg.Async.Enabled {
+		overflow, err := logger.ParseOverflowPolicy(cfg.Async.Overflow)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		async = &logger.AsyncConfig{
+			QueueSize: cfg.Async.QueueSize,
+			Overflow:  overflow,
+		}
+	}
+
+	var ring *logger.RingBuffer
+	if cfg.Ring.Size > 0 {
+		ring = logger.NewRingBuffer(cfg.Ring.Size)
+	}
+
+	log := logger.NewWithConfig(logger.Config{
+		ServiceName: "sales-api",
+		MinLevel:    minLevel,
+		TraceIDFn:   traceIDFn,
+		Events:      loggerEvents,
+		Sinks:       sinks,
+		Redact:      redact,
+		Sample:      sample,
+		Ring:        ring,
+		Async:       async,
+	})
+
+	return log, ring, closeFn, nil
+}
+
+// purgeUsers permanently removes the users deleted longer ago than the
+// retention, along with the expired tokens, on every interval until the
+// context is canceled. A zero interval turns purging off.
+func purgeUsers(ctx context.Context, log *logger.Logger, core *user.Core, interval time.Duration, retention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := core.Purge(ctx, retention)
+		if err != nil {
+			log.Error(ctx, "purge users", "msg", err)
+			continue
+		}
+
+		if n > 0 {
+			log.Info(ctx, "purge users", "purged", n, "retention", retention.String())
+		}
+
+		n, err = core.PurgeTokens(ctx)
+		if err != nil {
+			log.Error(ctx, "purge tokens", "msg", err)
+			continue
+		}
+
+		if n > 0 {
+			log.Info(ctx, "purge tokens", "purged", n)
+		}
+	}
+}
+
+// newMailer constructs the sender of the emails. The file sender writes them
+// to the folder for local development, the smtp sender delivers them.
+func newMailer(sender string, dir string, from string, smtpCfg mail.SMTPConfig) (mail.Sender, error) {
+	addr, err := stdmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("parsing from address: %w", err)
+	}
+
+	switch sender {
+	case "file":
+		f, err := mail.NewFile(dir, *addr)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+
+	case "smtp":
+		smtpCfg.From = *addr
+		return mail.NewSMTP(smtpCfg), nil
+	}
+
+	return nil, fmt.Errorf("unknown sender %q", sender)
+}
@@ -0,0 +1,83 @@
+// Package checkgrp maintains the group of handlers for health checking.
+package checkgrp
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/islamghany/service/foundation/health"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Handlers manages the set of check endpoints.
+type Handlers struct {
+	build   string
+	log     *logger.Logger
+	checker *health.Checker
+	started time.Time
+}
+
+// New constructs a Handlers api for the check group.
+func New(build string, log *logger.Logger, checker *health.Checker) *Handlers {
+	return &Handlers{
+		build:   build,
+		log:     log,
+		checker: checker,
+		started: time.Now().UTC(),
+	}
+}
+
+// Readiness checks if the dependencies the service relies on are ready and if
+// not, will return a 503 status. Do not respond by just returning an error
+// because further up in the call stack it will interpret that as a non-trusted
+// error.
+func (h *Handlers) Readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	report := h.checker.Run(ctx)
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+		h.log.Info(ctx, "readiness failure", "status", report.Status)
+	}
+
+	return web.Respond(ctx, w, report, status)
+}
+
+// Liveness returns simple status info if the service is alive. If the
+// app is deployed to a Kubernetes cluster, it will also return pod, node, and
+// namespace details via the Downward API. The Kubernetes environment variables
+// need to be set within your Pod/Deployment manifest.
+func (h *Handlers) Liveness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	data := struct {
+		Status     string `json:"status,omitempty"`
+		Build      string `json:"build,omitempty"`
+		Host       string `json:"host,omitempty"`
+		Name       string `json:"name,omitempty"`
+		PodIP      string `json:"podIP,omitempty"`
+		Node       string `json:"node,omitempty"`
+		Namespace  string `json:"namespace,omitempty"`
+		GOMAXPROCS int    `json:"GOMAXPROCS,omitempty"`
+		Uptime     string `json:"uptime,omitempty"`
+	}{
+		Status:     "up",
+		Build:      h.build,
+		Host:       host,
+		Name:       os.Getenv("KUBERNETES_NAME"),
+		PodIP:      os.Getenv("KUBERNETES_POD_IP"),
+		Node:       os.Getenv("KUBERNETES_NODE_NAME"),
+		Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Uptime:     time.Since(h.started).Round(time.Second).String(),
+	}
+
+	return web.Respond(ctx, w, data, http.StatusOK)
+}
@@ -0,0 +1,23 @@
+package checkgrp
+
+import (
+	"net/http"
+
+	"github.com/islamghany/service/foundation/health"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Build   string
+	Log     *logger.Logger
+	Checker *health.Checker
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	hdl := New(cfg.Build, cfg.Log, cfg.Checker)
+	app.Handle(http.MethodGet, "/readiness", hdl.Readiness)
+	app.Handle(http.MethodGet, "/liveness", hdl.Liveness)
+}
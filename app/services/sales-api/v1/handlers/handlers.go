@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"github.com/islamghany/service/app/services/sales-api/v1/handlers/checkgrp"
 	"github.com/islamghany/service/app/services/sales-api/v1/handlers/hackgrp"
+	"github.com/islamghany/service/app/services/sales-api/v1/handlers/usergrp"
 	v1 "github.com/islamghany/service/business/web/v1"
 	"github.com/islamghany/service/foundation/web"
 )
@@ -10,4 +12,25 @@ type Routes struct{}
 
 func (Routes) Add(app *web.App, cfg v1.APIMuxConfig) {
 	hackgrp.Routes(app)
+
+	checkgrp.Routes(app, checkgrp.Config{
+		Build:   cfg.Build,
+		Log:     cfg.Log,
+		Checker: cfg.Health,
+	})
+
+	usergrp.Routes(app, usergrp.Config{
+		Log:            cfg.Log,
+		DB:             cfg.DB,
+		Auth:           cfg.Auth,
+		Mailer:         cfg.Mailer,
+		Passwords:      cfg.Passwords,
+		ResetURL:       cfg.Accounts.ResetURL,
+		VerifyURL:      cfg.Accounts.VerifyURL,
+		ResetTokenTTL:  cfg.Accounts.ResetTokenTTL,
+		VerifyTokenTTL: cfg.Accounts.VerifyTokenTTL,
+		AttemptsPerIP:  cfg.Accounts.AttemptsPerIP,
+		AttemptsWindow: cfg.Accounts.AttemptsWindow,
+		ClientIPHeader: cfg.Accounts.ClientIPHeader,
+	})
 }
@@ -0,0 +1,66 @@
+package usergrp
+
+import (
+	"github.com/islamghany/service/foundation/validate"
+)
+
+// AppToken is the token returned to an authenticated user.
+type AppToken struct {
+	Token string `json:"token"`
+}
+
+// AppForgotPassword contains the information needed to send a password
+// reset email.
+type AppForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppForgotPassword) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppResetPassword contains the information needed to reset a password.
+type AppResetPassword struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	PasswordConfirm string `json:"passwordConfirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppResetPassword) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppRequestVerification contains the information needed to send an email
+// verification email.
+type AppRequestVerification struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppRequestVerification) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppVerifyEmail contains the information needed to verify an email.
+type AppVerifyEmail struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppVerifyEmail) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package usergrp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/core/user/stores/userdb"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/business/web/v1/auth"
+	"github.com/islamghany/service/business/web/v1/mid"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/mail"
+	"github.com/islamghany/service/foundation/throttle"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log    *logger.Logger
+	DB     *database.Cluster
+	Auth   *auth.Auth
+	Mailer mail.Sender
+
+	// Passwords is the password policy and hashing of the user core.
+	Passwords user.PasswordConfig
+
+	// ResetURL and VerifyURL are the pages the emailed links point to. The
+	// token is added to them as the token query parameter.
+	ResetURL  string
+	VerifyURL string
+
+	// ResetTokenTTL and VerifyTokenTTL are how long the emailed tokens can
+	// be used.
+	ResetTokenTTL  time.Duration
+	VerifyTokenTTL time.Duration
+
+	// AttemptsPerIP is how many calls a client IP can make to these
+	// endpoints within AttemptsWindow. The IP is read from ClientIPHeader
+	// when it's set.
+	AttemptsPerIP  int
+	AttemptsWindow time.Duration
+	ClientIPHeader string
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB), cfg.Passwords)
+
+	hdl := New(cfg.Log, usrCore, cfg.Auth, cfg.Mailer, Links{
+		ResetURL:       cfg.ResetURL,
+		VerifyURL:      cfg.VerifyURL,
+		ResetTokenTTL:  cfg.ResetTokenTTL,
+		VerifyTokenTTL: cfg.VerifyTokenTTL,
+	})
+
+	// The endpoints share the limit so a client can't spread its guesses
+	// over them. It's checked before the transaction is started so throttled
+	// requests never reach the database.
+	limit := mid.Throttle(cfg.Log, throttle.New(cfg.AttemptsPerIP, cfg.AttemptsWindow), cfg.ClientIPHeader)
+	tran := mid.BeginCommitRollback(cfg.Log, database.NewTxManager(cfg.DB.Primary()))
+
+	app.Handle(http.MethodGet, "/v1/users/token", hdl.Token, limit)
+	app.Handle(http.MethodPost, "/v1/users/password/forgot", hdl.ForgotPassword, limit, tran)
+	app.Handle(http.MethodPost, "/v1/users/password/reset", hdl.ResetPassword, limit, tran)
+	app.Handle(http.MethodPost, "/v1/users/verify/request", hdl.RequestVerification, limit, tran)
+	app.Handle(http.MethodPost, "/v1/users/verify", hdl.VerifyEmail, limit, tran)
+}
@@ -0,0 +1,291 @@
+// Package usergrp maintains the group of handlers for user access.
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	stdmail "net/mail"
+	"net/url"
+	"time"
+
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/data/transaction"
+	"github.com/islamghany/service/business/web/v1/auth"
+	"github.com/islamghany/service/business/web/v1/respond"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/mail"
+	"github.com/islamghany/service/foundation/validate"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Links describes the links emailed to the users and how long they work.
+type Links struct {
+	ResetURL       string
+	VerifyURL      string
+	ResetTokenTTL  time.Duration
+	VerifyTokenTTL time.Duration
+}
+
+// Handlers manages the set of user endpoints.
+type Handlers struct {
+	log    *logger.Logger
+	user   *user.Core
+	auth   *auth.Auth
+	mailer mail.Sender
+	links  Links
+}
+
+// New constructs a Handlers api for the user group.
+func New(log *logger.Logger, usrCore *user.Core, ath *auth.Auth, mailer mail.Sender, links Links) *Handlers {
+	return &Handlers{
+		log:    log,
+		user:   usrCore,
+		auth:   ath,
+		mailer: mailer,
+		links:  links,
+	}
+}
+
+// Token authenticates the user with the email and password given through
+// basic auth and returns a token for them. Authenticating rehashes a
+// password hashed with outdated settings and counts failed logins toward a
+// lockout, so the user core isn't run under a transaction: the failures
+// must be kept even though the response is an error. A locked out user gets
+// the same response as a wrong password.
+func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	email, password, ok := r.BasicAuth()
+	if !ok {
+		return respond.NewError(errors.New("must provide email and password in Basic auth"), http.StatusUnauthorized)
+	}
+
+	addr, err := stdmail.ParseAddress(email)
+	if err != nil {
+		return respond.NewError(user.ErrAuthenticationFailure, http.StatusUnauthorized)
+	}
+
+	usr, err := h.user.Authenticate(ctx, *addr, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAuthenticationFailure), errors.Is(err, user.ErrLocked):
+			return respond.NewError(user.ErrAuthenticationFailure, http.StatusUnauthorized)
+		case errors.Is(err, user.ErrDisabled), errors.Is(err, user.ErrDeleted):
+			return respond.NewError(err, http.StatusForbidden)
+		}
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	token, err := h.auth.GenerateToken(usr.ID.String(), roles)
+	if err != nil {
+		return fmt.Errorf("generatetoken: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, AppToken{Token: token}, http.StatusOK)
+}
+
+// ForgotPassword emails a password reset link to the user with the email.
+// The response is the same whether or not the email belongs to an active
+// user, and the email is sent in the background so it takes about as long
+// either way. The endpoint can't be used to find out who has an account.
+func (h *Handlers) ForgotPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppForgotPassword
+	if err := web.Decode(r, &app); err != nil {
+		return respond.NewError(err, http.StatusBadRequest)
+	}
+
+	core, err := h.executeUnderTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	usr, ok, err := h.activeUser(ctx, core, app.Email)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		token, err := core.IssueToken(ctx, usr, user.PurposePasswordReset, h.links.ResetTokenTTL)
+		if err != nil {
+			return fmt.Errorf("issuetoken: userID[%s]: %w", usr.ID, err)
+		}
+
+		link, err := tokenLink(h.links.ResetURL, token)
+		if err != nil {
+			return err
+		}
+
+		msg := mail.Message{
+			To:      stdmail.Address{Name: usr.Name, Address: usr.Email.Address},
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nA password reset was requested for your account. Use the link below to choose a new password:\n\n%s\n\nThe link works once and expires in %s. If you didn't ask for this, you can ignore this email.\n",
+				usr.Name, link, h.links.ResetTokenTTL),
+		}
+
+		h.send(ctx, msg)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ResetPassword sets a new password for the user the reset token was
+// emailed to.
+func (h *Handlers) ResetPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppResetPassword
+	if err := web.Decode(r, &app); err != nil {
+		return respond.NewError(err, http.StatusBadRequest)
+	}
+
+	core, err := h.executeUnderTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := core.ResetPassword(ctx, app.Token, app.Password); err != nil {
+		return tokenError(err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RequestVerification emails an email verification link to the user with
+// the email, unless it's already verified. Like ForgotPassword, the response
+// doesn't tell whether the email belongs to an active user.
+func (h *Handlers) RequestVerification(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppRequestVerification
+	if err := web.Decode(r, &app); err != nil {
+		return respond.NewError(err, http.StatusBadRequest)
+	}
+
+	core, err := h.executeUnderTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	usr, ok, err := h.activeUser(ctx, core, app.Email)
+	if err != nil {
+		return err
+	}
+
+	if ok && !usr.EmailVerified {
+		token, err := core.IssueToken(ctx, usr, user.PurposeVerifyEmail, h.links.VerifyTokenTTL)
+		if err != nil {
+			return fmt.Errorf("issuetoken: userID[%s]: %w", usr.ID, err)
+		}
+
+		link, err := tokenLink(h.links.VerifyURL, token)
+		if err != nil {
+			return err
+		}
+
+		msg := mail.Message{
+			To:      stdmail.Address{Name: usr.Name, Address: usr.Email.Address},
+			Subject: "Verify your email",
+			Body: fmt.Sprintf("Hi %s,\n\nUse the link below to confirm this is your email:\n\n%s\n\nThe link works once and expires in %s.\n",
+				usr.Name, link, h.links.VerifyTokenTTL),
+		}
+
+		h.send(ctx, msg)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// VerifyEmail marks the email of the user the verification token was
+// emailed to as verified.
+func (h *Handlers) VerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppVerifyEmail
+	if err := web.Decode(r, &app); err != nil {
+		return respond.NewError(err, http.StatusBadRequest)
+	}
+
+	core, err := h.executeUnderTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := core.VerifyEmail(ctx, app.Token); err != nil {
+		return tokenError(err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// =============================================================================
+
+// executeUnderTransaction returns the user core using the transaction of the
+// request, if one was started.
+func (h *Handlers) executeUnderTransaction(ctx context.Context) (*user.Core, error) {
+	if tx, ok := transaction.Get(ctx); ok {
+		return h.user.ExecuteUnderTransaction(tx)
+	}
+
+	return h.user, nil
+}
+
+// activeUser returns the user with the email and whether it exists and is
+// active.
+func (h *Handlers) activeUser(ctx context.Context, core *user.Core, email string) (user.User, bool, error) {
+	addr, err := stdmail.ParseAddress(email)
+	if err != nil {
+		return user.User{}, false, respond.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err := core.QueryByEmail(ctx, *addr)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.User{}, false, nil
+		}
+		return user.User{}, false, fmt.Errorf("querybyemail: %w", err)
+	}
+
+	if user.CheckActive(usr) != nil {
+		return user.User{}, false, nil
+	}
+
+	return usr, true, nil
+}
+
+// send hands the message to the mailer once the transaction of the request
+// is committed, so nothing is emailed about a change that was rolled back.
+// The mailer queues the message instead of talking to the mail server, and a
+// failure is only logged, so the response doesn't tell whether an email was
+// sent.
+func (h *Handlers) send(ctx context.Context, msg mail.Message) {
+	transaction.AfterCommit(ctx, func() {
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			h.log.Error(ctx, "send email", "subject", msg.Subject, "msg", err)
+		}
+	})
+}
+
+// tokenLink adds the token to the page URL as the token query parameter.
+func tokenLink(page string, token string) (string, error) {
+	u, err := url.Parse(page)
+	if err != nil {
+		return "", fmt.Errorf("parse link: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// tokenError converts the errors of consuming a token into trusted errors.
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, user.ErrInvalidToken), validate.IsFieldErrors(err):
+		return respond.NewError(err, http.StatusBadRequest)
+	case errors.Is(err, user.ErrDisabled), errors.Is(err, user.ErrDeleted):
+		return respond.NewError(err, http.StatusForbidden)
+	}
+
+	return err
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// filter represents the set of conditions a record must match to be shown.
+// Every condition that is set must match.
+type filter struct {
+	service  string
+	minLevel *slog.Level
+	traceID  string
+	since    time.Time
+	until    time.Time
+	attrs    []attrMatch
+}
+
+// active reports whether any condition is set.
+func (f filter) active() bool {
+	return f.service != "" || f.minLevel != nil || f.traceID != "" ||
+		!f.since.IsZero() || !f.until.IsZero() || len(f.attrs) > 0
+}
+
+// match reports whether the record matches every condition.
+func (f filter) match(m map[string]any) bool {
+	if f.service != "" {
+		if s, _ := m["service"].(string); strings.ToLower(s) != f.service {
+			return false
+		}
+	}
+
+	if f.minLevel != nil {
+		s, _ := m["level"].(string)
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil || level < *f.minLevel {
+			return false
+		}
+	}
+
+	if f.traceID != "" {
+		if s, _ := m["trace_id"].(string); s != f.traceID {
+			return false
+		}
+	}
+
+	if !f.since.IsZero() || !f.until.IsZero() {
+		s, _ := m["time"].(string)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return false
+		}
+
+		if !f.since.IsZero() && t.Before(f.since) {
+			return false
+		}
+
+		if !f.until.IsZero() && t.After(f.until) {
+			return false
+		}
+	}
+
+	for _, am := range f.attrs {
+		if !am.match(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// =============================================================================
+
+// attrMatch represents a condition on a single attribute, either an exact
+// value (key=value) or a regular expression (key~regex). Keys of nested
+// groups are separated by dots.
+type attrMatch struct {
+	key   string
+	value string
+	re    *regexp.Regexp
+}
+
+func parseAttrMatch(s string) (attrMatch, error) {
+	i := strings.IndexAny(s, "=~")
+	if i <= 0 {
+		return attrMatch{}, fmt.Errorf("invalid attribute match %q, expecting key=value or key~regex", s)
+	}
+
+	am := attrMatch{
+		key:   s[:i],
+		value: s[i+1:],
+	}
+
+	if s[i] == '~' {
+		re, err := regexp.Compile(am.value)
+		if err != nil {
+			return attrMatch{}, fmt.Errorf("attribute match %q: %w", s, err)
+		}
+		am.re = re
+	}
+
+	return am, nil
+}
+
+func (am attrMatch) match(m map[string]any) bool {
+	v, ok := lookup(m, am.key)
+	if !ok {
+		return false
+	}
+
+	s := fmt.Sprint(v)
+	if am.re != nil {
+		return am.re.MatchString(s)
+	}
+
+	return s == am.value
+}
+
+// lookup finds the value for a dot separated key, descending into nested
+// groups when the key isn't found as is.
+func lookup(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+
+	nested, ok := m[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	return lookup(nested, rest)
+}
+
+// attrMatches collects the repeated -where flag values.
+type attrMatches []attrMatch
+
+// String implements the flag.Value interface.
+func (ams *attrMatches) String() string {
+	s := make([]string, len(*ams))
+	for i, am := range *ams {
+		op := "="
+		if am.re != nil {
+			op = "~"
+		}
+		s[i] = am.key + op + am.value
+	}
+
+	return strings.Join(s, ",")
+}
+
+// Set implements the flag.Value interface.
+func (ams *attrMatches) Set(s string) error {
+	am, err := parseAttrMatch(s)
+	if err != nil {
+		return err
+	}
+
+	*ams = append(*ams, am)
+
+	return nil
+}
+
+// =============================================================================
+
+// parseLevel parses a minimum level such as "warn".
+func parseLevel(s string) (*slog.Level, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+
+	return &level, nil
+}
+
+// parseTime parses an RFC3339 time or a duration back from now, such as 15m.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.New("expecting an RFC3339 time or a duration")
+	}
+
+	return t, nil
+}
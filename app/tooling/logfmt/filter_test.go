@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	warn := slog.LevelWarn
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	record := map[string]any{
+		"service":  "SALES-API",
+		"time":     now.Format(time.RFC3339Nano),
+		"level":    "WARN",
+		"trace_id": "abc",
+		"msg":      "request completed",
+		"status":   float64(404),
+		"path":     "/v1/users/token",
+		"user":     map[string]any{"role": "ADMIN"},
+	}
+
+	tt := []struct {
+		name   string
+		filter filter
+		exp    bool
+	}{
+		{name: "empty", filter: filter{}, exp: true},
+		{name: "service", filter: filter{service: "sales-api"}, exp: true},
+		{name: "other service", filter: filter{service: "sales-admin"}, exp: false},
+		{name: "level", filter: filter{minLevel: &warn}, exp: true},
+		{name: "trace", filter: filter{traceID: "abc"}, exp: true},
+		{name: "other trace", filter: filter{traceID: "xyz"}, exp: false},
+		{name: "since", filter: filter{since: now.Add(-time.Minute)}, exp: true},
+		{name: "after since", filter: filter{since: now.Add(time.Minute)}, exp: false},
+		{name: "until", filter: filter{until: now.Add(-time.Minute)}, exp: false},
+		{name: "equal", filter: filter{attrs: attrs(t, "status=404")}, exp: true},
+		{name: "not equal", filter: filter{attrs: attrs(t, "status=200")}, exp: false},
+		{name: "regex", filter: filter{attrs: attrs(t, "path~^/v1/users/")}, exp: true},
+		{name: "nested", filter: filter{attrs: attrs(t, "user.role=ADMIN")}, exp: true},
+		{name: "missing key", filter: filter{attrs: attrs(t, "missing=1")}, exp: false},
+		{name: "every condition", filter: filter{traceID: "abc", attrs: attrs(t, "status=404", "path~^/v2/")}, exp: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := tst.filter.match(record); got != tst.exp {
+				t.Errorf("got %v, exp %v", got, tst.exp)
+			}
+		})
+	}
+}
+
+func TestFilterLevel(t *testing.T) {
+	warn := slog.LevelWarn
+	f := filter{minLevel: &warn}
+
+	tt := []struct {
+		level string
+		exp   bool
+	}{
+		{level: "DEBUG", exp: false},
+		{level: "INFO", exp: false},
+		{level: "WARN", exp: true},
+		{level: "ERROR", exp: true},
+		{level: "ERROR+2", exp: true},
+		{level: "", exp: false},
+	}
+
+	for _, tst := range tt {
+		if got := f.match(map[string]any{"level": tst.level}); got != tst.exp {
+			t.Errorf("level %q: got %v, exp %v", tst.level, got, tst.exp)
+		}
+	}
+}
+
+func TestParseAttrMatch(t *testing.T) {
+	tt := []struct {
+		expr  string
+		key   string
+		value string
+		regex bool
+		err   bool
+	}{
+		{expr: "status=200", key: "status", value: "200"},
+		{expr: "path~^/v1", key: "path", value: "^/v1", regex: true},
+		{expr: "msg=a=b", key: "msg", value: "a=b"},
+		{expr: "empty=", key: "empty", value: ""},
+		{expr: "=value", err: true},
+		{expr: "novalue", err: true},
+		{expr: "path~(", err: true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.expr, func(t *testing.T) {
+			am, err := parseAttrMatch(tst.expr)
+			if tst.err {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+
+			if am.key != tst.key || am.value != tst.value || (am.re != nil) != tst.regex {
+				t.Errorf("got %q %q regex[%v], exp %q %q regex[%v]", am.key, am.value, am.re != nil, tst.key, tst.value, tst.regex)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		value string
+		exp   time.Time
+		err   bool
+	}{
+		{value: "", exp: time.Time{}},
+		{value: "15m", exp: now.Add(-15 * time.Minute)},
+		{value: "2024-05-01T10:00:00Z", exp: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "yesterday", err: true},
+	}
+
+	for _, tst := range tt {
+		got, err := parseTime(tst.value, now)
+		if (err != nil) != tst.err {
+			t.Fatalf("%q: got error %v, exp error %v", tst.value, err, tst.err)
+		}
+		if !got.Equal(tst.exp) {
+			t.Errorf("%q: got %s, exp %s", tst.value, got, tst.exp)
+		}
+	}
+}
+
+func attrs(t *testing.T, exprs ...string) []attrMatch {
+	t.Helper()
+
+	var ams attrMatches
+	for _, expr := range exprs {
+		if err := ams.Set(expr); err != nil {
+			t.Fatalf("parsing %q: %v", expr, err)
+		}
+	}
+
+	return ams
+}
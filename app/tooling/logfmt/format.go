@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// knownKeys are the keys printed in the fixed portion of a line.
+var knownKeys = map[string]bool{
+	"service":  true,
+	"time":     true,
+	"file":     true,
+	"level":    true,
+	"trace_id": true,
+	"msg":      true,
+}
+
+// Set of ANSI escape sequences used to color the level.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+	colorGray   = "\033[90m"
+)
+
+// attr is a key value pair printed after the fixed portion of a line.
+type attr struct {
+	Key   string
+	Value any
+}
+
+// line is the data available to a -format template.
+type line struct {
+	Service string
+	Time    string
+	File    string
+	Level   string
+	TraceID string
+	Msg     string
+	Attrs   []attr
+	Fields  map[string]any
+}
+
+// formatter turns a record into a line of text.
+type formatter struct {
+	keys  []string
+	color bool
+	tmpl  *template.Template
+}
+
+// newFormatter constructs a formatter. The keys are printed first in the order
+// given, followed by any other keys in sorted order. The color mode is one of
+// auto, always or never, where auto colors only when stdout is a terminal.
+func newFormatter(keys string, colorMode string, format string) (*formatter, error) {
+	f := formatter{}
+
+	for _, k := range strings.Split(keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			f.keys = append(f.keys, k)
+		}
+	}
+
+	switch colorMode {
+	case "always":
+		f.color = true
+	case "never":
+		f.color = false
+	case "auto", "":
+		f.color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	default:
+		return nil, fmt.Errorf("unknown color mode %q, expecting auto, always or never", colorMode)
+	}
+
+	if format != "" {
+		funcs := template.FuncMap{
+			"color": f.colorLevel,
+			"get": func(fields map[string]any, key string) any {
+				v, _ := lookup(fields, key)
+				return v
+			},
+		}
+
+		tmpl, err := template.New("format").Funcs(funcs).Parse(format)
+		if err != nil {
+			return nil, fmt.Errorf("parsing format: %w", err)
+		}
+		f.tmpl = tmpl
+	}
+
+	return &f, nil
+}
+
+// format returns the record as a single line of text.
+func (f *formatter) format(m map[string]any) (string, error) {
+	l := f.line(m)
+
+	if f.tmpl != nil {
+		var b strings.Builder
+		if err := f.tmpl.Execute(&b, l); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	// Build out the know portions of the log in the order
+	// I want them in.
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s: %s: %s: %s: %s: %s",
+		l.Service,
+		l.Time,
+		l.File,
+		f.colorLevel(l.Level),
+		l.TraceID,
+		l.Msg,
+	))
+
+	// Add the rest of the keys in a stable order. It's nice to see the
+	// key[value] in this format.
+	for _, a := range l.Attrs {
+		b.WriteString(fmt.Sprintf(": %s[%v]", a.Key, a.Value))
+	}
+
+	return b.String(), nil
+}
+
+// line extracts the data of a record.
+func (f *formatter) line(m map[string]any) line {
+	// I like always having a traceid present in the logs.
+	traceID := "00000000-0000-0000-0000-000000000000"
+	if v, ok := m["trace_id"]; ok {
+		traceID = fmt.Sprintf("%v", v)
+	}
+
+	l := line{
+		Service: str(m["service"]),
+		Time:    str(m["time"]),
+		File:    str(m["file"]),
+		Level:   str(m["level"]),
+		TraceID: traceID,
+		Msg:     str(m["msg"]),
+		Fields:  m,
+	}
+
+	seen := make(map[string]bool, len(f.keys))
+	for _, k := range f.keys {
+		if v, ok := m[k]; ok && !knownKeys[k] {
+			l.Attrs = append(l.Attrs, attr{Key: k, Value: v})
+			seen[k] = true
+		}
+	}
+
+	rest := make([]string, 0, len(m))
+	for k := range m {
+		if !knownKeys[k] && !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, k := range rest {
+		l.Attrs = append(l.Attrs, attr{Key: k, Value: m[k]})
+	}
+
+	return l
+}
+
+// colorLevel wraps the level in the color for its severity.
+func (f *formatter) colorLevel(level string) string {
+	if !f.color {
+		return level
+	}
+
+	var c string
+	switch {
+	case strings.HasPrefix(level, "ERROR"):
+		c = colorRed
+	case strings.HasPrefix(level, "WARN"):
+		c = colorYellow
+	case strings.HasPrefix(level, "INFO"):
+		c = colorCyan
+	case strings.HasPrefix(level, "DEBUG"):
+		c = colorGray
+	default:
+		return level
+	}
+
+	return c + level + colorReset
+}
+
+// =============================================================================
+
+func str(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+// isTerminal reports whether the file is a character device such as a
+// terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	record := map[string]any{
+		"service":  "SALES-API",
+		"time":     "2024-05-01T12:00:00Z",
+		"file":     "main.go:10",
+		"level":    "INFO",
+		"trace_id": "abc",
+		"msg":      "startup",
+		"zeta":     "z",
+		"alpha":    float64(1),
+		"status":   "ok",
+		"user":     map[string]any{"role": "ADMIN"},
+	}
+
+	tt := []struct {
+		name   string
+		keys   string
+		color  string
+		format string
+		record map[string]any
+		exp    string
+	}{
+		{
+			name:   "sorted keys",
+			record: record,
+			exp:    "SALES-API: 2024-05-01T12:00:00Z: main.go:10: INFO: abc: startup: alpha[1]: status[ok]: user[map[role:ADMIN]]: zeta[z]",
+		},
+		{
+			name:   "keys first",
+			keys:   "zeta, status",
+			record: record,
+			exp:    "SALES-API: 2024-05-01T12:00:00Z: main.go:10: INFO: abc: startup: zeta[z]: status[ok]: alpha[1]: user[map[role:ADMIN]]",
+		},
+		{
+			name:   "color",
+			color:  "always",
+			record: map[string]any{"level": "ERROR", "msg": "failed"},
+			exp:    ": : : " + colorRed + "ERROR" + colorReset + ": 00000000-0000-0000-0000-000000000000: failed",
+		},
+		{
+			name:   "template",
+			format: `{{.Level}} {{.Msg}} {{get .Fields "user.role"}}{{range .Attrs}} {{.Key}}{{end}}`,
+			record: record,
+			exp:    "INFO startup ADMIN alpha status user zeta",
+		},
+		{
+			name:   "template color",
+			color:  "always",
+			format: `{{color .Level}}`,
+			record: map[string]any{"level": "WARN"},
+			exp:    colorYellow + "WARN" + colorReset,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			color := tst.color
+			if color == "" {
+				color = "never"
+			}
+
+			f, err := newFormatter(tst.keys, color, tst.format)
+			if err != nil {
+				t.Fatalf("newformatter: %v", err)
+			}
+
+			got, err := f.format(tst.record)
+			if err != nil {
+				t.Fatalf("format: %v", err)
+			}
+
+			if got != tst.exp {
+				t.Errorf("got  %q\nexp %q", got, tst.exp)
+			}
+		})
+	}
+}
+
+func TestNewFormatterErrors(t *testing.T) {
+	tt := []struct {
+		name   string
+		color  string
+		format string
+	}{
+		{name: "color mode", color: "sometimes"},
+		{name: "template", color: "never", format: "{{.Level"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if _, err := newFormatter("", tst.color, tst.format); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
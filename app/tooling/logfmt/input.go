@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pollInterval is how often followed files are checked for new data.
+const pollInterval = 250 * time.Millisecond
+
+// fileList collects the repeated -file flag values.
+type fileList []string
+
+// String implements the flag.Value interface.
+func (fl *fileList) String() string {
+	return fmt.Sprint(*fl)
+}
+
+// Set implements the flag.Value interface.
+func (fl *fileList) Set(s string) error {
+	*fl = append(*fl, s)
+	return nil
+}
+
+// expand resolves the globs into the list of files to read, in sorted order
+// per glob. A pattern that matches nothing is kept as is when following so
+// the file is picked up once it is created.
+func expand(patterns []string, follow bool) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob %q: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			if !follow {
+				return nil, fmt.Errorf("no files match %q", pattern)
+			}
+			matches = []string{pattern}
+		}
+
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// readLines sends every line read from the reader to the channel. Lines of
+// any length are supported.
+func readLines(r io.Reader, lines chan<- string) error {
+	br := bufio.NewReader(r)
+
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			lines <- trimNewline(line)
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// readFiles sends the lines of every file to the channel, one file after the
+// other. When follow is set the files are read concurrently and kept open,
+// like tail -F, and the function only returns on error.
+func readFiles(paths []string, follow bool, lines chan<- string) error {
+	if !follow {
+		for _, path := range paths {
+			if err := readFile(path, lines); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make(chan error, len(paths))
+
+	var wg sync.WaitGroup
+	wg.Add(len(paths))
+
+	for _, path := range paths {
+		go func(path string) {
+			defer wg.Done()
+			errs <- followFile(path, lines)
+		}(path)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	return errors.Join(collect(errs)...)
+}
+
+func readFile(path string, lines chan<- string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readLines(f, lines)
+}
+
+// followFile reads the file from the start and then waits for new lines.
+// When the file is rotated or truncated it is reopened from the start. A
+// trailing partial line is held back until its newline is written.
+func followFile(path string, lines chan<- string) error {
+	var (
+		f       *os.File
+		br      *bufio.Reader
+		partial string
+	)
+
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for {
+		if f == nil {
+			var err error
+			if f, err = os.Open(path); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				time.Sleep(pollInterval)
+				continue
+			}
+			br = bufio.NewReader(f)
+			partial = ""
+		}
+
+		line, err := br.ReadString('\n')
+		partial += line
+
+		switch {
+		case err == nil:
+			lines <- trimNewline(partial)
+			partial = ""
+			continue
+
+		case !errors.Is(err, io.EOF):
+			return err
+		}
+
+		// At the end of the file, check if it was rotated or truncated
+		// before waiting for more data.
+		if reopen, err := rotated(f, path); err != nil {
+			return err
+		} else if reopen {
+			if partial != "" {
+				lines <- partial
+			}
+			f.Close()
+			f = nil
+			continue
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+// rotated reports whether the path no longer refers to the open file or the
+// file was truncated below the current read offset.
+func rotated(f *os.File, path string) (bool, error) {
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(pathInfo, fileInfo) {
+		return true, nil
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+
+	return fileInfo.Size() < offset, nil
+}
+
+// =============================================================================
+
+func trimNewline(s string) string {
+	if n := len(s); n > 0 && s[n-1] == '\n' {
+		s = s[:n-1]
+		if n := len(s); n > 0 && s[n-1] == '\r' {
+			s = s[:n-1]
+		}
+	}
+
+	return s
+}
+
+func collect(errs <-chan error) []error {
+	var out []error
+	for err := range errs {
+		if err != nil {
+			out = append(out, err)
+		}
+	}
+
+	return out
+}
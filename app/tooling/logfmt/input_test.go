@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotated(t *testing.T) {
+	tt := []struct {
+		name   string
+		change func(t *testing.T, path string)
+		exp    bool
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, path string) {},
+			exp:    false,
+		},
+		{
+			name: "appended",
+			change: func(t *testing.T, path string) {
+				appendFile(t, path, "more\n")
+			},
+			exp: false,
+		},
+		{
+			name: "truncated",
+			change: func(t *testing.T, path string) {
+				if err := os.Truncate(path, 0); err != nil {
+					t.Fatal(err)
+				}
+			},
+			exp: true,
+		},
+		{
+			name: "renamed and recreated",
+			change: func(t *testing.T, path string) {
+				if err := os.Rename(path, path+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, path, "new\n")
+			},
+			exp: true,
+		},
+		{
+			name: "removed",
+			change: func(t *testing.T, path string) {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			exp: false,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "service.log")
+			appendFile(t, path, "first line\nsecond line\n")
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if _, err := io.Copy(io.Discard, f); err != nil {
+				t.Fatal(err)
+			}
+
+			tst.change(t, path)
+
+			got, err := rotated(f, path)
+			if err != nil {
+				t.Fatalf("rotated: %v", err)
+			}
+
+			if got != tst.exp {
+				t.Errorf("got %v, exp %v", got, tst.exp)
+			}
+		})
+	}
+}
+
+func TestFollowFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	appendFile(t, path, "one\npart")
+
+	lines := make(chan string, 10)
+	go followFile(path, lines)
+
+	expect(t, lines, "one")
+
+	appendFile(t, path, "ial\n")
+	expect(t, lines, "partial")
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "two\n")
+	expect(t, lines, "two")
+}
+
+func TestTrimNewline(t *testing.T) {
+	tt := []struct {
+		line string
+		exp  string
+	}{
+		{line: "text\n", exp: "text"},
+		{line: "text\r\n", exp: "text"},
+		{line: "text", exp: "text"},
+		{line: "\n", exp: ""},
+		{line: "", exp: ""},
+	}
+
+	for _, tst := range tt {
+		if got := trimNewline(tst.line); got != tst.exp {
+			t.Errorf("%q: got %q, exp %q", tst.line, got, tst.exp)
+		}
+	}
+}
+
+func appendFile(t *testing.T, path string, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expect(t *testing.T, lines <-chan string, exp string) {
+	t.Helper()
+
+	select {
+	case got := <-lines:
+		if got != exp {
+			t.Fatalf("got %q, exp %q", got, exp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q", exp)
+	}
+}
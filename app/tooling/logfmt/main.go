@@ -1,98 +1,205 @@
 // This program takes the structured log output and makes it readable.
+//
+// By default every record is printed as a line of text. Passing report as the
+// first argument groups the records by trace ID into a timeline per request,
+// and passing summary prints per path request counts, the status code
+// distribution and latency percentiles of the completed requests.
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-json-experiment/json"
 )
 
-var service string
+var (
+	service  string
+	level    string
+	traceID  string
+	since    string
+	until    string
+	where    attrMatches
+	keys     string
+	color    string
+	format   string
+	files    fileList
+	follow   bool
+	copyFile string
+)
 
 func init() {
 	flag.StringVar(&service, "service", "", "filter which service to see")
+	flag.StringVar(&level, "level", "", "filter the minimum level to see, such as warn")
+	flag.StringVar(&traceID, "trace", "", "filter a single trace ID")
+	flag.StringVar(&since, "since", "", "filter records at or after this RFC3339 time or duration ago, such as 15m")
+	flag.StringVar(&until, "until", "", "filter records at or before this RFC3339 time or duration ago")
+	flag.Var(&where, "where", "filter on an attribute as key=value or key~regex, can be repeated")
+	flag.StringVar(&keys, "keys", "", "comma separated keys to print first, the rest are printed sorted")
+	flag.StringVar(&color, "color", "auto", "color the level: auto, always or never")
+	flag.Var(&files, "file", "read from a file or glob instead of stdin, can be repeated")
+	flag.BoolVar(&follow, "follow", false, "keep reading the files as they grow and across rotations, like tail -F")
+	flag.StringVar(&copyFile, "copy", "", "append every shown record as is to this file")
+	flag.StringVar(&format, "format", "", "text/template for each line, such as '{{.Time}} {{color .Level}} {{.Msg}} {{get .Fields \"path\"}}'")
 }
 
-const logFile = "./logFile.jsonl"
+// Set of modes the program can run in.
+const (
+	modeStream  = "stream"
+	modeReport  = "report"
+	modeSummary = "summary"
+)
 
 func main() {
 	flag.Parse()
-	// open file, if not exist create new one
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
-	defer file.Close()
+	// The mode is the first argument. Flags may follow it, so parse again
+	// with what's left.
+	mode := modeStream
+	if args := flag.Args(); len(args) > 0 && (args[0] == modeReport || args[0] == modeSummary) {
+		mode = args[0]
+		if err := flag.CommandLine.Parse(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if mode != modeStream && follow {
+		log.Fatalf("%s needs the whole input and can't be used with -follow", mode)
+	}
+
+	f, err := newFilter(time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmtr, err := newFormatter(keys, color, format)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var b strings.Builder
+	// When a copy file is provided, every record shown is also appended to
+	// it as is.
+	var raw *os.File
+	if copyFile != "" {
+		raw, err = os.OpenFile(copyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer raw.Close()
+	}
+
+	paths, err := expand(files, follow)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	service := strings.ToLower(service)
+	// Read the input on its own goroutine so files can be followed
+	// concurrently.
+	lines := make(chan string, 100)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(lines)
+
+		switch {
+		case len(paths) > 0:
+			errs <- readFiles(paths, follow, lines)
+		default:
+			errs <- readLines(os.Stdin, lines)
+		}
+	}()
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		s := scanner.Text()
+	tl := newTimelines()
+	sum := newSummary()
 
+	for s := range lines {
 		m := make(map[string]any)
 		err := json.Unmarshal([]byte(s), &m)
 		if err != nil {
-			if service == "" {
+			if !f.active() && mode == modeStream {
 				fmt.Println(s)
 			}
 			continue
 		}
 
-		// If a service filter was provided, check.
-		if service != "" && strings.ToLower(m["service"].(string)) != service {
+		// If any filters were provided, check.
+		if !f.match(m) {
 			continue
 		}
 
-		// I like always having a traceid present in the logs.
-		traceID := "00000000-0000-0000-0000-000000000000"
-		if v, ok := m["trace_id"]; ok {
-			traceID = fmt.Sprintf("%v", v)
-		}
 		// append jsonl file
-		file.WriteString(s + "\n")
+		if raw != nil {
+			raw.WriteString(s + "\n")
+		}
 
-		// {"time":"2023-06-01T17:21:11.13704718Z","level":"INFO","msg":"startup","service":"SALES-API","GOMAXPROCS":1}
+		switch mode {
+		case modeReport:
+			tl.add(m)
+			continue
+		case modeSummary:
+			sum.add(m)
+			continue
+		}
 
-		// Build out the know portions of the log in the order
-		// I want them in.
-		b.Reset()
-		b.WriteString(fmt.Sprintf("%s: %s: %s: %s: %s: %s: ",
-			m["service"],
-			m["time"],
-			m["file"],
-			m["level"],
-			traceID,
-			m["msg"],
-		))
-
-		// Add the rest of the keys ignoring the ones we already
-		// added for the log.
-		for k, v := range m {
-			switch k {
-			case "service", "time", "file", "level", "trace_id", "msg":
-				continue
-			}
+		// {"time":"2023-06-01T17:21:11.13704718Z","level":"INFO","msg":"startup","service":"SALES-API","GOMAXPROCS":1}
 
-			// It's nice to see the key[value] in this format
-			// especially since map ordering is random.
-			b.WriteString(fmt.Sprintf("%s[%v]: ", k, v))
+		out, err := fmtr.format(m)
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		// Write the new log format, removing the last :
-		out := b.String()
-		fmt.Println(out[:len(out)-2])
+		fmt.Println(out)
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := <-errs; err != nil {
 		log.Println(err)
 	}
+
+	switch mode {
+	case modeReport:
+		tl.write(os.Stdout, fmtr)
+	case modeSummary:
+		sum.write(os.Stdout)
+	}
+}
+
+// newFilter constructs the filter from the command line flags.
+func newFilter(now time.Time) (filter, error) {
+	minLevel, err := parseLevel(level)
+	if err != nil {
+		return filter{}, fmt.Errorf("level: %w", err)
+	}
+
+	sinceTime, err := parseTime(since, now)
+	if err != nil {
+		return filter{}, fmt.Errorf("since: %w", err)
+	}
+
+	untilTime, err := parseTime(until, now)
+	if err != nil {
+		return filter{}, fmt.Errorf("until: %w", err)
+	}
+
+	// Attribute matches can also be provided as arguments.
+	attrs := where
+	for _, arg := range flag.Args() {
+		am, err := parseAttrMatch(arg)
+		if err != nil {
+			return filter{}, err
+		}
+		attrs = append(attrs, am)
+	}
+
+	f := filter{
+		service:  strings.ToLower(service),
+		minLevel: minLevel,
+		traceID:  traceID,
+		since:    sinceTime,
+		until:    untilTime,
+		attrs:    attrs,
+	}
+
+	return f, nil
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// zeroTraceID is logged for records written outside of a request.
+const zeroTraceID = "00000000-0000-0000-0000-000000000000"
+
+// timelines groups records by trace ID so each request can be read from
+// start to finish.
+type timelines struct {
+	order  []string
+	traces map[string][]map[string]any
+}
+
+func newTimelines() *timelines {
+	return &timelines{
+		traces: make(map[string][]map[string]any),
+	}
+}
+
+// add records the log under its trace ID. Records without a trace ID are not
+// part of any request and are ignored.
+func (tl *timelines) add(m map[string]any) {
+	traceID := str(m["trace_id"])
+	if traceID == "" || traceID == zeroTraceID {
+		return
+	}
+
+	if _, exists := tl.traces[traceID]; !exists {
+		tl.order = append(tl.order, traceID)
+	}
+
+	tl.traces[traceID] = append(tl.traces[traceID], m)
+}
+
+// write prints a timeline per request in the order the requests were first
+// seen. Every record shows its offset from the first record of the request.
+func (tl *timelines) write(w io.Writer, fmtr *formatter) {
+	for _, traceID := range tl.order {
+		records := tl.traces[traceID]
+
+		sort.SliceStable(records, func(i, j int) bool {
+			return recordTime(records[i]).Before(recordTime(records[j]))
+		})
+
+		start := recordTime(records[0])
+
+		var method, path, status, since string
+		for _, m := range records {
+			if v, ok := m["method"]; ok {
+				method = str(v)
+			}
+			if v, ok := m["path"]; ok {
+				path = str(v)
+			}
+			if v, ok := m["statuscode"]; ok {
+				status = str(v)
+			}
+			if v, ok := m["since"]; ok {
+				if d, ok := duration(v); ok {
+					since = d.String()
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "%s %s %s status[%s] since[%s] records[%d]\n", traceID, method, path, status, since, len(records))
+
+		for _, m := range records {
+			offset := recordTime(m).Sub(start)
+
+			var b strings.Builder
+			for _, a := range fmtr.line(m).Attrs {
+				b.WriteString(fmt.Sprintf(" %s[%v]", a.Key, a.Value))
+			}
+
+			fmt.Fprintf(w, "  +%-12s %s: %s: %s:%s\n", offset, str(m["file"]), fmtr.colorLevel(str(m["level"])), str(m["msg"]), b.String())
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+// =============================================================================
+
+// pathStats holds the completed requests seen for a path.
+type pathStats struct {
+	count    int
+	statuses map[string]int
+	since    []time.Duration
+}
+
+// summary aggregates the "request completed" records written by the logger
+// middleware.
+type summary struct {
+	paths    map[string]*pathStats
+	statuses map[string]int
+	total    int
+}
+
+func newSummary() *summary {
+	return &summary{
+		paths:    make(map[string]*pathStats),
+		statuses: make(map[string]int),
+	}
+}
+
+// add records the log if it reports a completed request.
+func (s *summary) add(m map[string]any) {
+	status, ok := m["statuscode"]
+	if !ok {
+		return
+	}
+
+	path, _, _ := strings.Cut(str(m["path"]), "?")
+
+	ps, exists := s.paths[path]
+	if !exists {
+		ps = &pathStats{statuses: make(map[string]int)}
+		s.paths[path] = ps
+	}
+
+	code := str(status)
+
+	ps.count++
+	ps.statuses[code]++
+	s.statuses[code]++
+	s.total++
+
+	if d, ok := duration(m["since"]); ok {
+		ps.since = append(ps.since, d)
+	}
+}
+
+// write prints the per path table followed by the status code distribution.
+func (s *summary) write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PATH\tCOUNT\tSTATUS\tP50\tP95\tP99")
+
+	paths := make([]string, 0, len(s.paths))
+	for path := range s.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ps := s.paths[path]
+
+		sort.Slice(ps.since, func(i, j int) bool { return ps.since[i] < ps.since[j] })
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			path,
+			ps.count,
+			distribution(ps.statuses),
+			percentile(ps.since, 50),
+			percentile(ps.since, 95),
+			percentile(ps.since, 99),
+		)
+	}
+
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "requests[%d] status[%s]\n", s.total, distribution(s.statuses))
+}
+
+// distribution returns the status codes and their counts in code order.
+func distribution(statuses map[string]int) string {
+	codes := make([]string, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%s:%d", code, statuses[code])
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// percentile returns the nearest rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) string {
+	if len(sorted) == 0 {
+		return "-"
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1].String()
+}
+
+// =============================================================================
+
+// duration converts the since field into a duration. The JSON handler writes
+// durations as nanoseconds while other handlers write them as strings.
+func duration(v any) (time.Duration, bool) {
+	switch x := v.(type) {
+	case float64:
+		return time.Duration(x), true
+	case string:
+		if d, err := time.ParseDuration(x); err == nil {
+			return d, true
+		}
+		if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+			return time.Duration(n), true
+		}
+	}
+
+	return 0, false
+}
+
+// recordTime returns the time of the record or the zero time.
+func recordTime(m map[string]any) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, str(m["time"]))
+	return t
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(ns ...int) []time.Duration {
+		d := make([]time.Duration, len(ns))
+		for i, n := range ns {
+			d[i] = time.Duration(n) * time.Millisecond
+		}
+		return d
+	}
+
+	tt := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		exp    string
+	}{
+		{name: "empty", sorted: nil, p: 50, exp: "-"},
+		{name: "single", sorted: ms(7), p: 99, exp: "7ms"},
+		{name: "p50 even", sorted: ms(1, 2, 3, 4), p: 50, exp: "2ms"},
+		{name: "p50 odd", sorted: ms(1, 2, 3, 4, 5), p: 50, exp: "3ms"},
+		{name: "p95", sorted: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20), p: 95, exp: "19ms"},
+		{name: "p99 small", sorted: ms(1, 2, 3), p: 99, exp: "3ms"},
+		{name: "p0", sorted: ms(1, 2, 3), p: 0, exp: "1ms"},
+		{name: "p100", sorted: ms(1, 2, 3), p: 100, exp: "3ms"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := percentile(tst.sorted, tst.p); got != tst.exp {
+				t.Errorf("got %s, exp %s", got, tst.exp)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tt := []struct {
+		value any
+		exp   time.Duration
+		ok    bool
+	}{
+		{value: float64(1500000), exp: 1500 * time.Microsecond, ok: true},
+		{value: "1.5ms", exp: 1500 * time.Microsecond, ok: true},
+		{value: "1500000", exp: 1500 * time.Microsecond, ok: true},
+		{value: "soon", ok: false},
+		{value: nil, ok: false},
+	}
+
+	for _, tst := range tt {
+		got, ok := duration(tst.value)
+		if ok != tst.ok || got != tst.exp {
+			t.Errorf("%v: got %s %v, exp %s %v", tst.value, got, ok, tst.exp, tst.ok)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	s := newSummary()
+
+	records := []map[string]any{
+		{"path": "/v1/users?page=1", "statuscode": float64(200), "since": "10ms"},
+		{"path": "/v1/users?page=2", "statuscode": float64(200), "since": "30ms"},
+		{"path": "/v1/users", "statuscode": float64(500), "since": "20ms"},
+		{"path": "/readiness", "statuscode": float64(200), "since": "1ms"},
+		{"msg": "not a completed request"},
+	}
+	for _, m := range records {
+		s.add(m)
+	}
+
+	var b strings.Builder
+	s.write(&b)
+
+	tt := []struct {
+		name string
+		exp  string
+	}{
+		{name: "path without query", exp: "/v1/users   3      200:2 500:1  20ms  30ms  30ms"},
+		{name: "other path", exp: "/readiness  1      200:1        1ms   1ms   1ms"},
+		{name: "totals", exp: "requests[4] status[200:3 500:1]"},
+	}
+
+	for _, tst := range tt {
+		if !strings.Contains(b.String(), tst.exp) {
+			t.Errorf("%s: got\n%s\nexp it to contain %q", tst.name, b.String(), tst.exp)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package commands contains the functionality for the set of commands
+// currently supported by the CLI tooling.
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Help prints the list of supported commands.
+func Help() {
+	fmt.Print(`Usage: sales-admin [config flags] <command> [command flags] [args]
+
+Commands:
+  genkey                                  generate a new private key as <kid>.pem in the keys folder
+  gentoken [flags]                        generate a token signed with an existing key
+      -kid      key ID of the signing key, defaults to the active key
+      -subject  ID of an enabled, not deleted user (required)
+      -roles    comma separated roles, such as ADMIN,USER (default USER)
+      -issuer   issuer of the token (default "service project")
+      -ttl      lifetime of the token (default 8760h)
+  migrate [-dry-run]                      apply the pending database migrations
+  migrate status                          list the migrations with their state and checksum
+  migrate rollback -to <version> [-dry-run]
+                                          revert the migrations newer than the version
+  seed                                    add the seed data to the database
+  useradd [flags] <name> <email> <password>
+                                          add a new user
+      -roles       comma separated roles (default USER)
+      -department  department of the user
+  users list [flags]                      list the users
+      -page     page number (default 1)
+      -rows     rows per page (default 50)
+      -deleted  include the deleted users
+  users disable <user_id>                 prevent the user from signing in
+  users enable <user_id>                  allow a disabled user to sign in
+  users delete <user_id>                  mark the user as deleted
+  users restore <user_id>                 bring back a deleted user
+  users unlock <user_id>                  let a locked out user log in again
+  users purge [-retention 720h]           remove the users deleted longer ago than the retention
+  help                                    print this message
+
+Run "sales-admin --help" to see the configuration flags and environment
+variables shared with the sales-api.
+`)
+}
+
+// splitList splits a comma separated value, ignoring empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
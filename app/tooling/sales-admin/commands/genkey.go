@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// GenKey creates a new private key and writes it as <kid>.pem into the keys
+// folder, where the kid is a newly generated ID.
+func GenKey(keysFolder string) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("generating key: %w", err)
+	}
+
+	if err := os.MkdirAll(keysFolder, 0700); err != nil {
+		return fmt.Errorf("creating keys folder: %w", err)
+	}
+
+	kid := uuid.NewString()
+	path := filepath.Join(keysFolder, kid+".pem")
+
+	// Never overwrite an existing key.
+	privateFile, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("creating private file: %w", err)
+	}
+	defer privateFile.Close()
+
+	// Construct a PEM block for the private key.
+	privateBlock := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+
+	// Write the private key to the private key file.
+	if err := pem.Encode(privateFile, &privateBlock); err != nil {
+		return fmt.Errorf("encoding to private file: %w", err)
+	}
+
+	fmt.Println("private key file generated:", path)
+	fmt.Println("kid:", kid)
+
+	return nil
+}
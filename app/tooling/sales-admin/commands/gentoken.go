@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/core/user/stores/userdb"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/logger"
+)
+
+// GenToken generates a JWT signed with the private key identified by the kid
+// found in the keys folder. The token claims are taken from the flags in args.
+// The subject must be the ID of a user that is neither disabled nor deleted.
+func GenToken(log *logger.Logger, cfg database.Config, args []string, keysFolder string, activeKID string) error {
+	fs := flag.NewFlagSet("gentoken", flag.ContinueOnError)
+	kid := fs.String("kid", activeKID, "key ID of the signing key")
+	subject := fs.String("subject", "", "subject of the token, usually a user ID")
+	roles := fs.String("roles", user.RoleUser.Name(), "comma separated roles")
+	issuer := fs.String("issuer", "service project", "issuer of the token")
+	ttl := fs.Duration("ttl", 8760*time.Hour, "lifetime of the token")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *subject == "" {
+		return errors.New("gentoken: subject is required")
+	}
+
+	if *kid == "" {
+		return errors.New("gentoken: kid is required")
+	}
+
+	userID, err := uuid.Parse(*subject)
+	if err != nil {
+		return fmt.Errorf("gentoken: parsing subject: %w", err)
+	}
+
+	if err := checkSubject(log, cfg, userID); err != nil {
+		return fmt.Errorf("gentoken: %w", err)
+	}
+
+	var tokenRoles []string
+	for _, name := range splitList(*roles) {
+		role, err := user.ParseRole(name)
+		if err != nil {
+			return fmt.Errorf("gentoken: %w", err)
+		}
+		tokenRoles = append(tokenRoles, role.Name())
+	}
+
+	pemData, err := os.ReadFile(filepath.Join(keysFolder, *kid+".pem"))
+	if err != nil {
+		return fmt.Errorf("reading private key: %w", err)
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+	if err != nil {
+		return fmt.Errorf("parsing private key: %w", err)
+	}
+
+	// Generating a token requires defining a set of claims. In this applications
+	// case, we only care about defining the subject and the user in question and
+	// the roles they have on the database.
+	//
+	// iss (issuer): Issuer of the JWT
+	// sub (subject): Subject of the JWT (the user)
+	// aud (audience): Recipient for which the JWT is intended
+	// exp (expiration time): Time after which the JWT expires
+	// nbf (not before time): Time before which the JWT must not be accepted for processing
+	// iat (issued at time): Time at which the JWT was issued; can be used to determine age of the JWT
+	// jti (JWT ID): Unique identifier; can be used to prevent the JWT from being replayed (allows a token to be used only once)
+	now := time.Now().UTC()
+
+	claims := struct {
+		jwt.RegisteredClaims
+		Roles []string
+	}{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   *subject,
+			Issuer:    *issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(*ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles: tokenRoles,
+	}
+
+	method := jwt.GetSigningMethod(jwt.SigningMethodRS256.Name)
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = *kid
+
+	str, err := token.SignedString(privateKey)
+	if err != nil {
+		return fmt.Errorf("signing token: %w", err)
+	}
+
+	fmt.Println("****************")
+	fmt.Println(str)
+	fmt.Println("****************")
+
+	return nil
+}
+
+// checkSubject refuses to issue a token to a user that doesn't exist, is
+// disabled or is deleted.
+func checkSubject(log *logger.Logger, cfg database.Config, userID uuid.UUID) error {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := user.NewCore(log, userdb.NewStore(log, db), user.PasswordConfig{})
+
+	usr, err := core.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("retrieve user: %w", err)
+	}
+
+	if err := user.CheckActive(usr); err != nil {
+		return fmt.Errorf("user %s: %w", userID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/islamghany/service/business/data/dbmigrate"
+	database "github.com/islamghany/service/business/data/dbsql"
+)
+
+// Migrate runs the migrate subcommand found in args. With no subcommand the
+// pending migrations are applied.
+//
+//	migrate [-dry-run]
+//	migrate status
+//	migrate rollback -to <version> [-dry-run]
+func Migrate(cfg database.Config, args []string) error {
+	sub := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate "+sub, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print the SQL without running it")
+	to := fs.Float64("to", -1, "version to roll back to, 0 reverts every migration")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch sub {
+	case "up":
+		if *dryRun {
+			migs, err := dbmigrate.Plan(ctx, db)
+			if err != nil {
+				return fmt.Errorf("plan migrations: %w", err)
+			}
+			printScripts(migs, false)
+			return nil
+		}
+
+		if err := dbmigrate.Migrate(ctx, db); err != nil {
+			return fmt.Errorf("migrate database: %w", err)
+		}
+
+		fmt.Println("migrations complete")
+		return nil
+
+	case "status":
+		statuses, err := dbmigrate.MigrationStatus(ctx, db)
+		if err != nil {
+			return fmt.Errorf("migration status: %w", err)
+		}
+		printStatus(statuses)
+		return nil
+
+	case "rollback":
+		if *to < 0 {
+			return errors.New("migrate rollback: -to is required")
+		}
+
+		if *dryRun {
+			migs, err := dbmigrate.PlanRollback(ctx, db, *to)
+			if err != nil {
+				return fmt.Errorf("plan rollback: %w", err)
+			}
+			printScripts(migs, true)
+			return nil
+		}
+
+		if err := dbmigrate.Rollback(ctx, db, *to); err != nil {
+			return fmt.Errorf("rollback database: %w", err)
+		}
+
+		fmt.Println("rollback complete")
+		return nil
+	}
+
+	return fmt.Errorf("migrate: unknown subcommand %q", sub)
+}
+
+// printScripts prints the up or down script of every migration.
+func printScripts(migs []dbmigrate.Migration, down bool) {
+	if len(migs) == 0 {
+		fmt.Println("-- nothing to do")
+		return
+	}
+
+	for _, mig := range migs {
+		script := mig.Up
+		if down {
+			script = mig.Down
+		}
+
+		fmt.Printf("-- Version: %v\n-- Description: %s\n%s\n\n", mig.Version, mig.Description, script)
+	}
+}
+
+// printStatus prints a line per migration.
+func printStatus(statuses []dbmigrate.Status) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tCHECKSUM\tDESCRIPTION")
+
+	for _, st := range statuses {
+		state := "pending"
+		switch {
+		case st.Missing:
+			state = "missing"
+		case st.Drift:
+			state = "drift"
+		case st.Applied:
+			state = "applied"
+		}
+
+		appliedAt := "-"
+		if st.Applied {
+			appliedAt = st.AppliedAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(tw, "%v\t%s\t%s\t%s\t%s\n", st.Version, state, appliedAt, st.Checksum, st.Description)
+	}
+
+	tw.Flush()
+}
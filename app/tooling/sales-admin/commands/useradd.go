@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/core/user/stores/userdb"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/logger"
+)
+
+// UserAdd adds new users into the database. The name, email and password are
+// taken from args, after any flags. The password must follow the policy of
+// the passwords configuration.
+func UserAdd(log *logger.Logger, cfg database.Config, pwCfg user.PasswordConfig, args []string) error {
+	fs := flag.NewFlagSet("useradd", flag.ContinueOnError)
+	roles := fs.String("roles", user.RoleUser.Name(), "comma separated roles")
+	department := fs.String("department", "", "department of the user")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 3 {
+		return errors.New("useradd: expecting <name> <email> <password>")
+	}
+	name, email, password := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return fmt.Errorf("parsing email: %w", err)
+	}
+
+	var usrRoles []user.Role
+	for _, value := range splitList(*roles) {
+		role, err := user.ParseRole(value)
+		if err != nil {
+			return fmt.Errorf("useradd: %w", err)
+		}
+		usrRoles = append(usrRoles, role)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := user.NewCore(log, userdb.NewStore(log, db), pwCfg)
+
+	nu := user.NewUser{
+		Name:            name,
+		Email:           *addr,
+		Password:        password,
+		PasswordConfirm: password,
+		Roles:           usrRoles,
+		Department:      *department,
+	}
+
+	usr, err := core.Create(ctx, nu)
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+
+	fmt.Println("user id:", usr.ID)
+	return nil
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	"github.com/islamghany/service/business/core/user/stores/userdb"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/logger"
+)
+
+// Users runs the users subcommand found in args.
+func Users(log *logger.Logger, cfg database.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("users: expecting a subcommand, such as list")
+	}
+
+	switch args[0] {
+	case "list":
+		return usersList(log, cfg, args[1:])
+
+	case "purge":
+		return usersPurge(log, cfg, args[1:])
+
+	case "disable", "enable", "delete", "restore", "unlock":
+		return usersChange(log, cfg, args[0], args[1:])
+	}
+
+	return fmt.Errorf("users: unknown subcommand %q", args[0])
+}
+
+// usersChange applies the action to the user with the ID found in args.
+func usersChange(log *logger.Logger, cfg database.Config, action string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("users %s: expecting <user_id>", action)
+	}
+
+	userID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("users %s: parsing user id: %w", action, err)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := user.NewCore(log, userdb.NewStore(log, db), user.PasswordConfig{})
+
+	usr, err := core.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("retrieve user: %w", err)
+	}
+
+	switch action {
+	case "disable":
+		_, err = core.Disable(ctx, usr)
+	case "enable":
+		_, err = core.Enable(ctx, usr)
+	case "delete":
+		_, err = core.Delete(ctx, usr)
+	case "restore":
+		_, err = core.Restore(ctx, usr)
+	case "unlock":
+		_, err = core.Unlock(ctx, usr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s user: %w", action, err)
+	}
+
+	fmt.Printf("user %s: %s done\n", userID, action)
+	return nil
+}
+
+// usersPurge permanently removes the users deleted longer ago than the
+// retention.
+func usersPurge(log *logger.Logger, cfg database.Config, args []string) error {
+	fs := flag.NewFlagSet("users purge", flag.ContinueOnError)
+	retention := fs.Duration("retention", 720*time.Hour, "how long deleted users are kept")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	core := user.NewCore(log, userdb.NewStore(log, db), user.PasswordConfig{})
+
+	n, err := core.Purge(ctx, *retention)
+	if err != nil {
+		return fmt.Errorf("purge users: %w", err)
+	}
+
+	fmt.Println("users purged:", n)
+	return nil
+}
+
+// usersList prints a page of users.
+func usersList(log *logger.Logger, cfg database.Config, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	page := fs.Int("page", 1, "page number")
+	rows := fs.Int("rows", 50, "rows per page")
+	deleted := fs.Bool("deleted", false, "include the deleted users")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *page < 1 || *rows < 1 {
+		return errors.New("users list: page and rows must be positive")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := user.NewCore(log, userdb.NewStore(log, db), user.PasswordConfig{})
+
+	var filter user.QueryFilter
+	if *deleted {
+		filter.WithIncludeDeleted()
+	}
+
+	users, err := core.Query(ctx, filter, user.DefaultOrderBy, *page, *rows)
+	if err != nil {
+		return fmt.Errorf("retrieve users: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tROLES\tDEPARTMENT\tENABLED\tLOCKED UNTIL\tCREATED\tDELETED")
+
+	for _, usr := range users {
+		roles := make([]string, len(usr.Roles))
+		for i, role := range usr.Roles {
+			roles[i] = role.Name()
+		}
+
+		locked := "-"
+		if time.Now().Before(usr.LockedUntil) {
+			locked = usr.LockedUntil.Format(time.DateTime)
+		}
+
+		deleted := "-"
+		if !usr.DateDeleted.IsZero() {
+			deleted = usr.DateDeleted.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\n",
+			usr.ID,
+			usr.Name,
+			usr.Email.Address,
+			strings.Join(roles, ","),
+			usr.Department,
+			usr.Enabled,
+			locked,
+			usr.DateCreated.Format(time.DateTime),
+			deleted,
+		)
+	}
+
+	return tw.Flush()
+}
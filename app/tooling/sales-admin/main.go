@@ -1,123 +1,157 @@
+// This program performs administrative tasks for the sales service.
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"time"
 
-	// jwt
-
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/ardanlabs/conf/v3"
+	"github.com/islamghany/service/app/tooling/sales-admin/commands"
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/logger"
 )
 
-func main() {
-	err := gentoken()
-	if err != nil {
-		log.Fatal(err)
+var build = "develop"
+
+// config is the configuration shared with the sales-api. The same SALES
+// prefixed environment variables configure both programs.
+type config struct {
+	conf.Version
+	Args conf.Args
+	DB   struct {
+		User             string `conf:"default:postgres"`
+		Password         string `conf:"default:postgres,mask"`
+		Host             string `conf:"default:database-service.sales-system.svc.cluster.local"`
+		Name             string `conf:"default:postgres"`
+		Schema           string
+		MaxIdleConns     int           `conf:"default:2"`
+		MaxOpenConns     int           `conf:"default:0"`
+		ConnMaxLifetime  time.Duration `conf:"default:30m"`
+		ConnMaxIdleTime  time.Duration `conf:"default:5m"`
+		StatementTimeout time.Duration `conf:"default:0s"`
+		DisableTLS       bool          `conf:"default:true"`
+	}
+	Passwords struct {
+		MinLength        int           `conf:"default:10"`
+		MaxLength        int           `conf:"default:72"`
+		MinClasses       int           `conf:"default:3"`
+		Algorithm        string        `conf:"default:bcrypt,help:bcrypt or argon2id"`
+		BcryptCost       int           `conf:"default:10"`
+		Argon2Time       uint32        `conf:"default:2"`
+		Argon2Memory     uint32        `conf:"default:19456"`
+		Argon2Threads    uint8         `conf:"default:1"`
+		LockoutThreshold int           `conf:"default:5"`
+		LockoutWindow    time.Duration `conf:"default:1m"`
+		LockoutMaxWindow time.Duration `conf:"default:24h"`
+	}
+	Auth struct {
+		KeysFolder string `conf:"default:zarf/keys/"`
+		ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
 	}
-
 }
 
-func gentoken() error {
-	privateKey, err := genKe()
-	if err != nil {
-		return fmt.Errorf("generating key: %w", err)
+func main() {
+	log := logger.New(os.Stdout, logger.LevelInfo, "ADMIN", func(context.Context) string { return "" })
+
+	if err := run(log); err != nil {
+		fmt.Println("msg:", err)
+		os.Exit(1)
 	}
-	// Generating a token requires defining a set of claims. In this applications
-	// case, we only care about defining the subject and the user in question and
-	// the roles they have on the database. This token will expire in a year.
-	//
-	// iss (issuer): Issuer of the JWT
-	// sub (subject): Subject of the JWT (the user)
-	// aud (audience): Recipient for which the JWT is intended
-	// exp (expiration time): Time after which the JWT expires
-	// nbf (not before time): Time before which the JWT must not be accepted for processing
-	// iat (issued at time): Time at which the JWT was issued; can be used to determine age of the JWT
-	// jti (JWT ID): Unique identifier; can be used to prevent the JWT from being replayed (allows a token to be used only once)
-	claims := struct {
-		jwt.RegisteredClaims
-		Roles []string
-	}{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   "12345678789",
-			Issuer:    "service project",
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(8760 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+}
+
+func run(log *logger.Logger) error {
+	cfg := config{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "Service Project",
 		},
-		Roles: []string{"ADMIN"},
 	}
 
-	method := jwt.GetSigningMethod(jwt.SigningMethodRS256.Name)
-
-	token := jwt.NewWithClaims(method, claims)
-	token.Header["kid"] = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
-
-	str, err := token.SignedString(privateKey)
+	const prefix = "SALES"
+	help, err := conf.Parse(prefix, &cfg)
 	if err != nil {
-		return fmt.Errorf("signing token: %w", err)
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
 	}
 
-	fmt.Println("****************")
-	fmt.Println(str)
-	fmt.Println("****************")
-	return nil
+	return processCommands(cfg.Args, log, cfg)
 }
 
-func genKe() (*rsa.PrivateKey, error) {
-	// Generate a new private key.
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, fmt.Errorf("generating key: %w", err)
+// processCommands handles the execution of the commands specified on
+// the command line.
+func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
+	dbConfig := database.Config{
+		User:             cfg.DB.User,
+		Password:         cfg.DB.Password,
+		Host:             cfg.DB.Host,
+		Name:             cfg.DB.Name,
+		Schema:           cfg.DB.Schema,
+		MaxIdleConns:     cfg.DB.MaxIdleConns,
+		MaxOpenConns:     cfg.DB.MaxOpenConns,
+		ConnMaxLifetime:  cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.DB.ConnMaxIdleTime,
+		StatementTimeout: cfg.DB.StatementTimeout,
+		DisableTLS:       cfg.DB.DisableTLS,
 	}
-	// Create a file for the private key information in PEM form.
-	privateFile, err := os.Create("private.pem")
-	if err != nil {
-		return nil, fmt.Errorf("creating private file: %w", err)
-	}
-	defer privateFile.Close()
 
-	// Construct a PEM block for the private key.
-	privateBlock := pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	pwCfg := user.PasswordConfig{
+		MinLength:        cfg.Passwords.MinLength,
+		MaxLength:        cfg.Passwords.MaxLength,
+		MinClasses:       cfg.Passwords.MinClasses,
+		Algorithm:        cfg.Passwords.Algorithm,
+		BcryptCost:       cfg.Passwords.BcryptCost,
+		Argon2Time:       cfg.Passwords.Argon2Time,
+		Argon2Memory:     cfg.Passwords.Argon2Memory,
+		Argon2Threads:    cfg.Passwords.Argon2Threads,
+		LockoutThreshold: cfg.Passwords.LockoutThreshold,
+		LockoutWindow:    cfg.Passwords.LockoutWindow,
+		LockoutMaxWindow: cfg.Passwords.LockoutMaxWindow,
+	}
+	if err := pwCfg.Validate(); err != nil {
+		return fmt.Errorf("passwords config: %w", err)
 	}
 
-	// Write the private key to the private key file.
-	if err := pem.Encode(privateFile, &privateBlock); err != nil {
-		return nil, fmt.Errorf("encoding to private file: %w", err)
+	rest := []string(args)
+	if len(rest) > 0 {
+		rest = rest[1:]
 	}
 
-	// =========================================================================
+	switch args.Num(0) {
+	case "genkey":
+		return commands.GenKey(cfg.Auth.KeysFolder)
 
-	// Create a file for the public key information in PEM form.
-	publicFile, err := os.Create("public.pem")
-	if err != nil {
-		return nil, fmt.Errorf("creating public file: %w", err)
-	}
-	defer publicFile.Close()
+	case "gentoken":
+		return commands.GenToken(log, dbConfig, rest, cfg.Auth.KeysFolder, cfg.Auth.ActiveKID)
 
-	// Marshal the public key from the private key to PKIX.
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling public key: %w", err)
-	}
+	case "migrate":
+		return commands.Migrate(dbConfig, rest)
 
-	// Construct a PEM block for the public key.
-	publicBlock := pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: asn1Bytes,
-	}
-	// Write the public key to the public key file.
-	if err := pem.Encode(publicFile, &publicBlock); err != nil {
-		return nil, fmt.Errorf("encoding to public file: %w", err)
-	}
+	case "seed":
+		return commands.Seed(dbConfig)
+
+	case "useradd":
+		return commands.UserAdd(log, dbConfig, pwCfg, rest)
 
-	fmt.Println("private and public key files generated")
+	case "users":
+		return commands.Users(log, dbConfig, rest)
 
-	return privateKey, nil
+	case "help":
+		commands.Help()
+		return nil
+
+	case "":
+		commands.Help()
+		return errors.New("missing command")
+
+	default:
+		commands.Help()
+		return fmt.Errorf("unknown command %q", args.Num(0))
+	}
 }
@@ -16,6 +16,9 @@ type QueryFilter struct {
 	Email            *mail.Address `validate:"omitempty"`
 	StartCreatedDate *time.Time    `validate:"omitempty"`
 	EndCreatedDate   *time.Time    `validate:"omitempty"`
+
+	// IncludeDeleted adds the deleted users, which are left out by default.
+	IncludeDeleted bool
 }
 
 // Validate checks the data in the model is considered clean.
@@ -52,3 +55,8 @@ func (qf *QueryFilter) WithEndCreatedDate(endDate time.Time) {
 	d := endDate.UTC()
 	qf.EndCreatedDate = &d
 }
+
+// WithIncludeDeleted sets the IncludeDeleted field of the QueryFilter value.
+func (qf *QueryFilter) WithIncludeDeleted() {
+	qf.IncludeDeleted = true
+}
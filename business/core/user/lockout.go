@@ -0,0 +1,65 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Unlock lets a locked out user log in again and clears their failed
+// logins.
+func (c *Core) Unlock(ctx context.Context, usr User) (User, error) {
+	lockedUntil := usr.LockedUntil
+
+	if err := c.storer.ResetFailedLogins(ctx, usr.ID); err != nil {
+		return User{}, fmt.Errorf("resetfailedlogins: %w", err)
+	}
+
+	usr.FailedLogins = 0
+	usr.LockedUntil = time.Time{}
+
+	c.log.Info(ctx, "security event", "event", "account_unlocked", "userID", usr.ID, "lockedUntil", lockedUntil)
+
+	return usr, nil
+}
+
+// recordFailedLogin counts the failed login and locks the user out when
+// the count reaches the lockout threshold.
+func (c *Core) recordFailedLogin(ctx context.Context, usr User) error {
+	if c.passwords.LockoutThreshold < 0 {
+		return nil
+	}
+
+	failed, err := c.storer.RecordFailedLogin(ctx, usr.ID)
+	if err != nil {
+		return fmt.Errorf("recordfailedlogin: %w", err)
+	}
+
+	if failed < c.passwords.LockoutThreshold {
+		return nil
+	}
+
+	window := c.lockoutWindow(failed)
+	until := time.Now().Add(window)
+
+	if err := c.storer.LockUntil(ctx, usr.ID, until); err != nil {
+		return fmt.Errorf("lockuntil: %w", err)
+	}
+
+	c.log.Warn(ctx, "security event", "event", "account_locked", "userID", usr.ID, "failedLogins", failed, "window", window.String(), "lockedUntil", until)
+
+	return nil
+}
+
+// lockoutWindow returns how long the user is locked out after the number
+// of failed logins. The window doubles with every failed login past the
+// threshold.
+func (c *Core) lockoutWindow(failed int) time.Duration {
+	window := c.passwords.LockoutWindow
+
+	for i := c.passwords.LockoutThreshold; i < failed && window < c.passwords.LockoutMaxWindow; i++ {
+		window *= 2
+	}
+
+	return min(window, c.passwords.LockoutMaxWindow)
+}
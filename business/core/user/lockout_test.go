@@ -0,0 +1,88 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLockoutWindow(t *testing.T) {
+	c := Core{
+		passwords: PasswordConfig{
+			LockoutThreshold: 5,
+			LockoutWindow:    time.Minute,
+			LockoutMaxWindow: 10 * time.Minute,
+		},
+	}
+
+	tt := []struct {
+		failed int
+		exp    time.Duration
+	}{
+		{failed: 5, exp: time.Minute},
+		{failed: 6, exp: 2 * time.Minute},
+		{failed: 7, exp: 4 * time.Minute},
+		{failed: 8, exp: 8 * time.Minute},
+		{failed: 9, exp: 10 * time.Minute},
+		{failed: 100, exp: 10 * time.Minute},
+	}
+
+	for _, tst := range tt {
+		if got := c.lockoutWindow(tst.failed); got != tst.exp {
+			t.Errorf("failed %d: got %s, exp %s", tst.failed, got, tst.exp)
+		}
+	}
+}
+
+func TestAuthenticateLocked(t *testing.T) {
+	cfg := PasswordConfig{BcryptCost: bcrypt.MinCost, LockoutThreshold: 5}.withDefaults()
+
+	hash, err := cfg.hashPassword("gophers")
+	if err != nil {
+		t.Fatalf("hashing: %v", err)
+	}
+
+	tt := []struct {
+		name        string
+		lockedUntil time.Time
+		password    string
+		err         error
+		failed      int
+	}{
+		{name: "right password", password: "gophers", err: nil, failed: 0},
+		{name: "wrong password", password: "wrong", err: ErrAuthenticationFailure, failed: 2},
+		{name: "locked right password", lockedUntil: time.Now().Add(time.Hour), password: "gophers", err: ErrLocked, failed: 1},
+		{name: "locked wrong password", lockedUntil: time.Now().Add(time.Hour), password: "wrong", err: ErrLocked, failed: 1},
+		{name: "lockout expired", lockedUntil: time.Now().Add(-time.Hour), password: "gophers", err: nil, failed: 0},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			usr := User{
+				ID:           uuid.New(),
+				Email:        mail.Address{Address: "bill@example.com"},
+				PasswordHash: hash,
+				Enabled:      true,
+				FailedLogins: 1,
+				LockedUntil:  tst.lockedUntil,
+			}
+
+			store := newMemStore(usr)
+			c := Core{storer: store, passwords: cfg}
+
+			_, err := c.Authenticate(context.Background(), usr.Email, tst.password)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("got %v, exp %v", err, tst.err)
+			}
+
+			if got := store.users[usr.ID].FailedLogins; got != tst.failed {
+				t.Errorf("got %d failed logins, exp %d", got, tst.failed)
+			}
+		})
+	}
+}
@@ -9,15 +9,19 @@ import (
 
 // User represents information about an individual user.
 type User struct {
-	ID           uuid.UUID
-	Name         string
-	Email        mail.Address
-	Roles        []Role
-	PasswordHash []byte
-	Department   string
-	Enabled      bool
-	DateCreated  time.Time
-	DateUpdated  time.Time
+	ID            uuid.UUID
+	Name          string
+	Email         mail.Address
+	Roles         []Role
+	PasswordHash  []byte `log:"redact"`
+	Department    string
+	Enabled       bool
+	EmailVerified bool
+	FailedLogins  int
+	LockedUntil   time.Time // zero unless the user was locked out
+	DateCreated   time.Time
+	DateUpdated   time.Time
+	DateDeleted   time.Time // zero unless the user was deleted
 }
 
 // NewUser contains information needed to create a new user.
@@ -26,8 +30,8 @@ type NewUser struct {
 	Email           mail.Address
 	Roles           []Role
 	Department      string
-	Password        string
-	PasswordConfirm string
+	Password        string `log:"redact"`
+	PasswordConfirm string `log:"redact"`
 }
 
 // UpdateUser contains information needed to update a user.
@@ -36,7 +40,17 @@ type UpdateUser struct {
 	Email           *mail.Address
 	Roles           []Role
 	Department      *string
-	Password        *string
-	PasswordConfirm *string
+	Password        *string `log:"redact"`
+	PasswordConfirm *string `log:"redact"`
 	Enabled         *bool
 }
+
+// Token represents a single-use token sent to a user to prove they own
+// their email. Only the hash of the token is stored.
+type Token struct {
+	Hash        string
+	UserID      uuid.UUID
+	Purpose     Purpose
+	DateExpires time.Time
+	DateCreated time.Time
+}
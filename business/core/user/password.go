@@ -0,0 +1,363 @@
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/islamghany/service/foundation/validate"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of algorithms passwords can be hashed with.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// PasswordConfig holds the password policy, how passwords are hashed and
+// when failed logins lock a user out. Zero values use the defaults.
+type PasswordConfig struct {
+	// MinLength is the minimum number of characters, 10 by default.
+	MinLength int
+
+	// MaxLength is the maximum number of bytes, 72 by default since bcrypt
+	// ignores anything longer.
+	MaxLength int
+
+	// MinClasses is how many of the lower case, upper case, digit and
+	// symbol character classes must be used, 3 by default. A negative value
+	// turns the check off.
+	MinClasses int
+
+	// Algorithm is AlgorithmBcrypt, the default, or AlgorithmArgon2id.
+	Algorithm string
+
+	// BcryptCost is the bcrypt cost, bcrypt.DefaultCost by default.
+	BcryptCost int
+
+	// Argon2Time, Argon2Memory in KiB and Argon2Threads are the argon2id
+	// parameters, 2 passes over 19MiB with 1 thread by default.
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+
+	// LockoutThreshold is the number of failed logins in a row that lock
+	// the user out, 5 by default. A negative value turns lockout off.
+	LockoutThreshold int
+
+	// LockoutWindow is how long the first lockout lasts, 1m by default.
+	// Every failed login after it doubles the window, up to
+	// LockoutMaxWindow, 24h by default.
+	LockoutWindow    time.Duration
+	LockoutMaxWindow time.Duration
+}
+
+// withDefaults returns the configuration with the zero values replaced by
+// the defaults.
+func (cfg PasswordConfig) withDefaults() PasswordConfig {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 10
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 72
+	}
+	if cfg.MinClasses == 0 {
+		cfg.MinClasses = 3
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmBcrypt
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = bcrypt.DefaultCost
+	}
+	if cfg.Argon2Time == 0 {
+		cfg.Argon2Time = 2
+	}
+	if cfg.Argon2Memory == 0 {
+		cfg.Argon2Memory = 19 * 1024
+	}
+	if cfg.Argon2Threads == 0 {
+		cfg.Argon2Threads = 1
+	}
+	if cfg.LockoutThreshold == 0 {
+		cfg.LockoutThreshold = 5
+	}
+	if cfg.LockoutWindow <= 0 {
+		cfg.LockoutWindow = time.Minute
+	}
+	if cfg.LockoutMaxWindow <= 0 {
+		cfg.LockoutMaxWindow = 24 * time.Hour
+	}
+
+	return cfg
+}
+
+// Validate checks the configuration can be used.
+func (cfg PasswordConfig) Validate() error {
+	cfg = cfg.withDefaults()
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost %d out of range [%d, %d]", cfg.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		if cfg.MaxLength > 72 {
+			return fmt.Errorf("max length %d is over the 72 bytes bcrypt supports", cfg.MaxLength)
+		}
+
+	case AlgorithmArgon2id:
+
+	default:
+		return fmt.Errorf("unknown algorithm %q", cfg.Algorithm)
+	}
+
+	if cfg.MinLength > cfg.MaxLength {
+		return fmt.Errorf("min length %d is over the max length %d", cfg.MinLength, cfg.MaxLength)
+	}
+
+	if cfg.LockoutWindow > cfg.LockoutMaxWindow {
+		return fmt.Errorf("lockout window %s is over the max window %s", cfg.LockoutWindow, cfg.LockoutMaxWindow)
+	}
+
+	if cfg.MinClasses > 4 {
+		return fmt.Errorf("min classes %d is over the 4 classes", cfg.MinClasses)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+//go:embed denylist.txt
+var denyListDoc []byte
+
+// denyList holds the common passwords that are refused, in lower case.
+var denyList = func() map[string]struct{} {
+	m := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(denyListDoc))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m[strings.ToLower(line)] = struct{}{}
+	}
+
+	return m
+}()
+
+// checkPassword returns field errors for the password field when the
+// password doesn't follow the policy. The name and email are those of the
+// user the password is for.
+func (cfg PasswordConfig) checkPassword(password string, name string, email mail.Address) error {
+	var violations []string
+
+	if n := utf8.RuneCountInString(password); n < cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", cfg.MinLength))
+	}
+
+	if len(password) > cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes", cfg.MaxLength))
+	}
+
+	if cfg.MinClasses > 0 {
+		if n := characterClasses(password); n < cfg.MinClasses {
+			violations = append(violations, fmt.Sprintf("must use at least %d of lower case, upper case, digit and symbol characters", cfg.MinClasses))
+		}
+	}
+
+	lower := strings.ToLower(password)
+
+	if _, exists := denyList[lower]; exists {
+		violations = append(violations, "is too common")
+	}
+
+	if containsPersonal(lower, name, email) {
+		violations = append(violations, "must not contain the name or email")
+	}
+
+	if len(violations) > 0 {
+		return validate.NewFieldsError("password", errors.New(strings.Join(violations, "; ")))
+	}
+
+	return nil
+}
+
+// characterClasses returns how many character classes the password uses.
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// containsPersonal reports whether the lower cased password contains a part
+// of the name or the local part of the email. Parts shorter than 3
+// characters are too common to be checked.
+func containsPersonal(lower string, name string, email mail.Address) bool {
+	parts := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if local, _, ok := strings.Cut(strings.ToLower(email.Address), "@"); ok {
+		parts = append(parts, local)
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(lower, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// =============================================================================
+
+// argon2Prefix starts the argon2id hashes, which are stored in the PHC
+// string format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+const argon2Prefix = "$argon2id$"
+
+// Length of the argon2id salt and key in bytes.
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// argon2Params holds the parameters an argon2id hash was made with.
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// hashPassword hashes the password with the configured algorithm.
+func (cfg PasswordConfig) hashPassword(password string) ([]byte, error) {
+	switch cfg.Algorithm {
+	case AlgorithmArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("salt: %w", err)
+		}
+
+		key := argon2.IDKey([]byte(password), salt, cfg.Argon2Time, cfg.Argon2Memory, cfg.Argon2Threads, argon2KeyLen)
+
+		hash := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+		return []byte(hash), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	return hash, nil
+}
+
+// comparePassword returns nil when the password matches the hash, made with
+// either algorithm.
+func comparePassword(hash []byte, password string) error {
+	if !bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		return bcrypt.CompareHashAndPassword(hash, []byte(password))
+	}
+
+	p, err := parseArgon2(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return errors.New("password doesn't match")
+	}
+
+	return nil
+}
+
+// needsRehash reports whether the hash was made with another algorithm or
+// weaker parameters than the configured ones.
+func (cfg PasswordConfig) needsRehash(hash []byte) bool {
+	if bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		if cfg.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+
+		p, err := parseArgon2(hash)
+		if err != nil {
+			return true
+		}
+
+		return p.time < cfg.Argon2Time || p.memory < cfg.Argon2Memory || p.threads < cfg.Argon2Threads
+	}
+
+	if cfg.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return true
+	}
+
+	return cost < cfg.BcryptCost
+}
+
+// parseArgon2 extracts the parameters from an argon2id hash.
+func parseArgon2(hash []byte) (argon2Params, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return argon2Params{}, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2Params{}, fmt.Errorf("parsing version: %w", err)
+	}
+	if version != argon2.Version {
+		return argon2Params{}, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return argon2Params{}, fmt.Errorf("parsing parameters: %w", err)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Params{}, fmt.Errorf("decoding salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return argon2Params{}, fmt.Errorf("decoding key: %w", err)
+	}
+
+	return p, nil
+}
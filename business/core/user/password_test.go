@@ -0,0 +1,114 @@
+package user
+
+import (
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/islamghany/service/foundation/validate"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	cfg := PasswordConfig{}.withDefaults()
+	email := mail.Address{Address: "bill.kennedy@example.com"}
+
+	tt := []struct {
+		name     string
+		cfg      PasswordConfig
+		password string
+		valid    bool
+	}{
+		{name: "valid", cfg: cfg, password: "Tr0ub4dor&3x", valid: true},
+		{name: "too short", cfg: cfg, password: "Ab1!", valid: false},
+		{name: "too long", cfg: cfg, password: "Ab1!" + string(make([]byte, 72)), valid: false},
+		{name: "too few classes", cfg: cfg, password: "alllowercaseletters", valid: false},
+		{name: "classes turned off", cfg: PasswordConfig{MinClasses: -1}.withDefaults(), password: "alllowercaseletters", valid: true},
+		{name: "common", cfg: PasswordConfig{MinLength: 8, MinClasses: -1}.withDefaults(), password: "Password", valid: false},
+		{name: "contains name", cfg: cfg, password: "xyzBill2024!", valid: false},
+		{name: "contains email", cfg: cfg, password: "9bill.kennedy!X", valid: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			err := tst.cfg.checkPassword(tst.password, "Bill Kennedy", email)
+
+			if tst.valid {
+				if err != nil {
+					t.Fatalf("got %v, exp no error", err)
+				}
+				return
+			}
+
+			if !validate.IsFieldErrors(err) {
+				t.Fatalf("got %v, exp field errors", err)
+			}
+		})
+	}
+}
+
+func TestPasswordConfigValidate(t *testing.T) {
+	tt := []struct {
+		name  string
+		cfg   PasswordConfig
+		valid bool
+	}{
+		{name: "defaults", cfg: PasswordConfig{}, valid: true},
+		{name: "argon2id", cfg: PasswordConfig{Algorithm: AlgorithmArgon2id, MaxLength: 128}, valid: true},
+		{name: "unknown algorithm", cfg: PasswordConfig{Algorithm: "md5"}, valid: false},
+		{name: "bcrypt cost", cfg: PasswordConfig{BcryptCost: bcrypt.MaxCost + 1}, valid: false},
+		{name: "bcrypt max length", cfg: PasswordConfig{MaxLength: 128}, valid: false},
+		{name: "min over max", cfg: PasswordConfig{MinLength: 50, MaxLength: 40}, valid: false},
+		{name: "classes", cfg: PasswordConfig{MinClasses: 5}, valid: false},
+		{name: "lockout window", cfg: PasswordConfig{LockoutWindow: 2 * time.Hour, LockoutMaxWindow: time.Hour}, valid: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if err := tst.cfg.Validate(); (err == nil) != tst.valid {
+				t.Errorf("got %v, exp valid %v", err, tst.valid)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	bcryptCfg := PasswordConfig{BcryptCost: bcrypt.MinCost}.withDefaults()
+	argonCfg := PasswordConfig{Algorithm: AlgorithmArgon2id, Argon2Memory: 64}.withDefaults()
+
+	tt := []struct {
+		name   string
+		cfg    PasswordConfig
+		rehash PasswordConfig
+	}{
+		{name: "bcrypt", cfg: bcryptCfg, rehash: argonCfg},
+		{name: "argon2id", cfg: argonCfg, rehash: bcryptCfg},
+		{name: "bcrypt cost", cfg: bcryptCfg, rehash: PasswordConfig{BcryptCost: bcrypt.MinCost + 1}.withDefaults()},
+		{name: "argon2id memory", cfg: argonCfg, rehash: PasswordConfig{Algorithm: AlgorithmArgon2id, Argon2Memory: 128}.withDefaults()},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			hash, err := tst.cfg.hashPassword("gophers")
+			if err != nil {
+				t.Fatalf("hashing: %v", err)
+			}
+
+			if err := comparePassword(hash, "gophers"); err != nil {
+				t.Errorf("got %v comparing the right password", err)
+			}
+
+			if err := comparePassword(hash, "wrong"); err == nil {
+				t.Error("got no error comparing the wrong password")
+			}
+
+			if tst.cfg.needsRehash(hash) {
+				t.Error("got a rehash needed with the same settings")
+			}
+
+			if !tst.rehash.needsRehash(hash) {
+				t.Error("got no rehash needed with stronger settings")
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package user
+
+import "fmt"
+
+// Set of possible purposes for a token.
+var (
+	PurposePasswordReset = Purpose{"PASSWORD_RESET"}
+	PurposeVerifyEmail   = Purpose{"VERIFY_EMAIL"}
+)
+
+// Set of known purposes.
+var purposes = map[string]Purpose{
+	PurposePasswordReset.name: PurposePasswordReset,
+	PurposeVerifyEmail.name:   PurposeVerifyEmail,
+}
+
+// Purpose represents what a token can be used for.
+type Purpose struct {
+	name string
+}
+
+// ParsePurpose parses the string value and returns a purpose if one exists.
+func ParsePurpose(value string) (Purpose, error) {
+	purpose, exists := purposes[value]
+	if !exists {
+		return Purpose{}, fmt.Errorf("invalid purpose %q", value)
+	}
+
+	return purpose, nil
+}
+
+// Name returns the name of the purpose.
+func (p Purpose) Name() string {
+	return p.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Purpose) Equal(p2 Purpose) bool {
+	return p.name == p2.name
+}
@@ -0,0 +1,46 @@
+package userdb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/islamghany/service/business/core/user"
+)
+
+func applyFilter(filter user.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["user_id"] = *filter.ID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = "%" + *filter.Name + "%"
+		wc = append(wc, "name LIKE :name")
+	}
+
+	if filter.Email != nil {
+		data["email"] = (*filter.Email).Address
+		wc = append(wc, "email = :email")
+	}
+
+	if filter.StartCreatedDate != nil {
+		data["start_date_created"] = filter.StartCreatedDate.UTC()
+		wc = append(wc, "date_created >= :start_date_created")
+	}
+
+	if filter.EndCreatedDate != nil {
+		data["end_date_created"] = filter.EndCreatedDate.UTC()
+		wc = append(wc, "date_created <= :end_date_created")
+	}
+
+	if !filter.IncludeDeleted {
+		wc = append(wc, "date_deleted IS NULL")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
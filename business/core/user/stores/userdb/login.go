@@ -0,0 +1,94 @@
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+)
+
+// RecordFailedLogin adds a failed login to the user and returns the number
+// of failed logins since the last successful one. The count is incremented
+// in the database so concurrent failures are all counted.
+func (s *Store) RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		failed_logins = failed_logins + 1
+	WHERE
+		user_id = :user_id
+	RETURNING
+		failed_logins`
+
+	var dest struct {
+		FailedLogins int `db:"failed_logins"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dest); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return 0, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return dest.FailedLogins, nil
+}
+
+// LockUntil refuses the logins of the user until the specified time.
+func (s *Store) LockUntil(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	data := struct {
+		UserID      string    `db:"user_id"`
+		LockedUntil time.Time `db:"locked_until"`
+	}{
+		UserID:      userID.String(),
+		LockedUntil: until.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		locked_until = :locked_until
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// ResetFailedLogins clears the failed logins and the lockout of the user.
+func (s *Store) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		failed_logins = 0,
+		locked_until = NULL
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
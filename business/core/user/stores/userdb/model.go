@@ -0,0 +1,114 @@
+package userdb
+
+import (
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+)
+
+// dbUser represent the structure we need for moving data
+// between the app and the database.
+type dbUser struct {
+	ID            uuid.UUID            `db:"user_id"`
+	Name          string               `db:"name"`
+	Email         string               `db:"email"`
+	Roles         database.StringArray `db:"roles"`
+	PasswordHash  []byte               `db:"password_hash"`
+	Department    sql.NullString       `db:"department"`
+	Enabled       bool                 `db:"enabled"`
+	EmailVerified bool                 `db:"email_verified"`
+	FailedLogins  int                  `db:"failed_logins"`
+	LockedUntil   sql.NullTime         `db:"locked_until"`
+	DateCreated   time.Time            `db:"date_created"`
+	DateUpdated   time.Time            `db:"date_updated"`
+	DateDeleted   sql.NullTime         `db:"date_deleted"`
+}
+
+func toDBUser(usr user.User) dbUser {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	return dbUser{
+		ID:           usr.ID,
+		Name:         usr.Name,
+		Email:        usr.Email.Address,
+		Roles:        roles,
+		PasswordHash: usr.PasswordHash,
+		Department: sql.NullString{
+			String: usr.Department,
+			Valid:  usr.Department != "",
+		},
+		Enabled:       usr.Enabled,
+		EmailVerified: usr.EmailVerified,
+		FailedLogins:  usr.FailedLogins,
+		LockedUntil: sql.NullTime{
+			Time:  usr.LockedUntil.UTC(),
+			Valid: !usr.LockedUntil.IsZero(),
+		},
+		DateCreated: usr.DateCreated.UTC(),
+		DateUpdated: usr.DateUpdated.UTC(),
+		DateDeleted: sql.NullTime{
+			Time:  usr.DateDeleted.UTC(),
+			Valid: !usr.DateDeleted.IsZero(),
+		},
+	}
+}
+
+func toCoreUser(dbUsr dbUser) (user.User, error) {
+	addr := mail.Address{
+		Address: dbUsr.Email,
+	}
+
+	roles := make([]user.Role, len(dbUsr.Roles))
+	for i, value := range dbUsr.Roles {
+		role, err := user.ParseRole(value)
+		if err != nil {
+			return user.User{}, fmt.Errorf("parse role: %w", err)
+		}
+		roles[i] = role
+	}
+
+	usr := user.User{
+		ID:            dbUsr.ID,
+		Name:          dbUsr.Name,
+		Email:         addr,
+		Roles:         roles,
+		PasswordHash:  dbUsr.PasswordHash,
+		Enabled:       dbUsr.Enabled,
+		EmailVerified: dbUsr.EmailVerified,
+		FailedLogins:  dbUsr.FailedLogins,
+		Department:    dbUsr.Department.String,
+		DateCreated:   dbUsr.DateCreated.In(time.Local),
+		DateUpdated:   dbUsr.DateUpdated.In(time.Local),
+	}
+
+	if dbUsr.LockedUntil.Valid {
+		usr.LockedUntil = dbUsr.LockedUntil.Time.In(time.Local)
+	}
+
+	if dbUsr.DateDeleted.Valid {
+		usr.DateDeleted = dbUsr.DateDeleted.Time.In(time.Local)
+	}
+
+	return usr, nil
+}
+
+func toCoreUserSlice(dbUsers []dbUser) ([]user.User, error) {
+	usrs := make([]user.User, len(dbUsers))
+	for i, dbUsr := range dbUsers {
+		usr, err := toCoreUser(dbUsr)
+		if err != nil {
+			return nil, err
+		}
+		usrs[i] = usr
+	}
+
+	return usrs, nil
+}
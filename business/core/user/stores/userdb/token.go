@@ -0,0 +1,178 @@
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+)
+
+// dbToken represent the structure we need for moving tokens between the app
+// and the database.
+type dbToken struct {
+	Hash        string    `db:"token_hash"`
+	UserID      uuid.UUID `db:"user_id"`
+	Purpose     string    `db:"purpose"`
+	DateExpires time.Time `db:"date_expires"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toDBToken(tkn user.Token) dbToken {
+	return dbToken{
+		Hash:        tkn.Hash,
+		UserID:      tkn.UserID,
+		Purpose:     tkn.Purpose.Name(),
+		DateExpires: tkn.DateExpires.UTC(),
+		DateCreated: tkn.DateCreated.UTC(),
+	}
+}
+
+func toCoreToken(dbTkn dbToken) (user.Token, error) {
+	purpose, err := user.ParsePurpose(dbTkn.Purpose)
+	if err != nil {
+		return user.Token{}, fmt.Errorf("parse purpose: %w", err)
+	}
+
+	tkn := user.Token{
+		Hash:        dbTkn.Hash,
+		UserID:      dbTkn.UserID,
+		Purpose:     purpose,
+		DateExpires: dbTkn.DateExpires.In(time.Local),
+		DateCreated: dbTkn.DateCreated.In(time.Local),
+	}
+
+	return tkn, nil
+}
+
+// =============================================================================
+
+// CreateToken inserts a new token into the database.
+func (s *Store) CreateToken(ctx context.Context, tkn user.Token) error {
+	const q = `
+	INSERT INTO user_tokens
+		(token_hash, user_id, purpose, date_expires, date_created)
+	VALUES
+		(:token_hash, :user_id, :purpose, :date_expires, :date_created)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBToken(tkn)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryToken gets the token with the specified hash and purpose from the
+// database without removing it.
+func (s *Store) QueryToken(ctx context.Context, hash string, purpose user.Purpose) (user.Token, error) {
+	data := struct {
+		Hash    string `db:"token_hash"`
+		Purpose string `db:"purpose"`
+	}{
+		Hash:    hash,
+		Purpose: purpose.Name(),
+	}
+
+	const q = `
+	SELECT
+		token_hash, user_id, purpose, date_expires, date_created
+	FROM
+		user_tokens
+	WHERE
+		token_hash = :token_hash AND purpose = :purpose`
+
+	var dbTkn dbToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTkn); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.Token{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.Token{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreToken(dbTkn)
+}
+
+// ConsumeToken removes the token with the specified hash and purpose from
+// the database and returns it. Removing it in the same statement makes sure
+// the token is used only once.
+func (s *Store) ConsumeToken(ctx context.Context, hash string, purpose user.Purpose) (user.Token, error) {
+	data := struct {
+		Hash    string `db:"token_hash"`
+		Purpose string `db:"purpose"`
+	}{
+		Hash:    hash,
+		Purpose: purpose.Name(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		token_hash = :token_hash AND purpose = :purpose
+	RETURNING
+		token_hash, user_id, purpose, date_expires, date_created`
+
+	var dbTkn dbToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTkn); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.Token{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.Token{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreToken(dbTkn)
+}
+
+// DeleteTokens removes the tokens issued to the user for the purpose from
+// the database.
+func (s *Store) DeleteTokens(ctx context.Context, userID uuid.UUID, purpose user.Purpose) error {
+	data := struct {
+		UserID  string `db:"user_id"`
+		Purpose string `db:"purpose"`
+	}{
+		UserID:  userID.String(),
+		Purpose: purpose.Name(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND purpose = :purpose`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeTokens removes the tokens that expired before the specified time from
+// the database and returns how many were removed.
+func (s *Store) PurgeTokens(ctx context.Context, expiredBefore time.Time) (int, error) {
+	data := struct {
+		ExpiredBefore time.Time `db:"expired_before"`
+	}{
+		ExpiredBefore: expiredBefore.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		date_expires < :expired_before
+	RETURNING
+		token_hash`
+
+	var hashes []struct {
+		Hash string `db:"token_hash"`
+	}
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &hashes); err != nil {
+		return 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return len(hashes), nil
+}
@@ -1,6 +1,18 @@
 package userdb
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/business/data/order"
+	"github.com/islamghany/service/business/data/transaction"
 	"github.com/islamghany/service/foundation/logger"
 	"github.com/jmoiron/sqlx"
 )
@@ -11,10 +23,208 @@ type Store struct {
 	db  sqlx.ExtContext
 }
 
-// NewStore constructs the api for data access.
-func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+// NewStore constructs the api for data access. The db is a *sqlx.DB or a
+// *database.Cluster when reads can be served by replicas.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
 	return &Store{
 		log: log,
 		db:  db,
 	}
 }
+
+// ExecuteUnderTransaction constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) ExecuteUnderTransaction(tx transaction.CommitRollbacker) (user.Storer, error) {
+	ec, err := database.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		log: s.log,
+		db:  ec,
+	}, nil
+}
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, usr user.User) error {
+	const q = `
+	INSERT INTO users
+		(user_id, name, email, password_hash, roles, department, enabled, email_verified, date_created, date_updated)
+	VALUES
+		(:user_id, :name, :email, :password_hash, :roles, :department, :enabled, :email_verified, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a user document in the database. The failed logins and
+// the lockout are left alone, they only change through the login queries.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	const q = `
+	UPDATE
+		users
+	SET
+		"name" = :name,
+		"email" = :email,
+		"roles" = :roles,
+		"password_hash" = :password_hash,
+		"department" = :department,
+		"enabled" = :enabled,
+		"email_verified" = :email_verified,
+		"date_updated" = :date_updated,
+		"date_deleted" = :date_deleted
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes the users deleted before the specified time from the
+// database and returns how many were removed.
+func (s *Store) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	data := struct {
+		DeletedBefore time.Time `db:"deleted_before"`
+	}{
+		DeletedBefore: deletedBefore.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		users
+	WHERE
+		date_deleted IS NOT NULL AND date_deleted < :deleted_before
+	RETURNING
+		user_id`
+
+	var ids []struct {
+		ID uuid.UUID `db:"user_id"`
+	}
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &ids); err != nil {
+		return 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	data := map[string]any{
+		"offset":        (pageNumber - 1) * rowsPerPage,
+		"rows_per_page": rowsPerPage,
+	}
+
+	const q = `
+	SELECT
+		user_id, name, email, password_hash, roles, department, enabled, email_verified, failed_logins, locked_until, date_created, date_updated, date_deleted
+	FROM
+		users`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(" ORDER BY " + orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbUsrs []dbUser
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
+
+// Count returns the total number of users in the DB.
+func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		users`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		user_id, name, email, password_hash, roles, department, enabled, email_verified, failed_logins, locked_until, date_created, date_updated, date_deleted
+	FROM
+		users
+	WHERE
+		user_id = :user_id`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User, error) {
+	data := struct {
+		Email string `db:"email"`
+	}{
+		Email: email.Address,
+	}
+
+	const q = `
+	SELECT
+		user_id, name, email, password_hash, roles, department, enabled, email_verified, failed_logins, locked_until, date_created, date_updated, date_deleted
+	FROM
+		users
+	WHERE
+		email = :email`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
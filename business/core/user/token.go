@@ -0,0 +1,167 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidToken is returned for a token that is unknown, was already used
+// or has expired. The cases aren't told apart so nothing is learned about
+// the tokens by guessing.
+var ErrInvalidToken = errors.New("token is invalid or expired")
+
+// IssueToken creates a token for the purpose that is valid for the ttl and
+// returns it. The token is meant to be sent to the user's email, only its
+// hash is kept.
+func (c *Core) IssueToken(ctx context.Context, usr User, purpose Purpose, ttl time.Duration) (string, error) {
+	if err := CheckActive(usr); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+
+	tkn := Token{
+		Hash:        hashToken(token),
+		UserID:      usr.ID,
+		Purpose:     purpose,
+		DateExpires: now.Add(ttl),
+		DateCreated: now,
+	}
+
+	if err := c.storer.CreateToken(ctx, tkn); err != nil {
+		return "", fmt.Errorf("createtoken: %w", err)
+	}
+
+	return token, nil
+}
+
+// ResetPassword sets a new password for the user the password reset token
+// was issued to. Receiving the token proves the user owns their email, so
+// it's marked as verified too and any lockout is lifted. Any other reset
+// token of the user stops working. A password that doesn't follow the policy
+// is refused before the token is consumed, so the link can be used again.
+func (c *Core) ResetPassword(ctx context.Context, token string, password string) (User, error) {
+	tkn, err := c.storer.QueryToken(ctx, hashToken(token), PurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, ErrInvalidToken
+		}
+		return User{}, fmt.Errorf("querytoken: %w", err)
+	}
+
+	usr, err := c.tokenUser(ctx, tkn)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := c.passwords.checkPassword(password, usr.Name, usr.Email); err != nil {
+		return User{}, fmt.Errorf("checkpassword: %w", err)
+	}
+
+	if usr, err = c.consumeToken(ctx, token, PurposePasswordReset); err != nil {
+		return User{}, err
+	}
+
+	hash, err := c.passwords.hashPassword(password)
+	if err != nil {
+		return User{}, fmt.Errorf("hashpassword: %w", err)
+	}
+
+	if err := c.storer.ResetFailedLogins(ctx, usr.ID); err != nil {
+		return User{}, fmt.Errorf("resetfailedlogins: %w", err)
+	}
+
+	usr.PasswordHash = hash
+	usr.EmailVerified = true
+	usr.FailedLogins = 0
+	usr.LockedUntil = time.Time{}
+
+	return c.update(ctx, usr)
+}
+
+// VerifyEmail marks the email of the user the verification token was issued
+// to as verified.
+func (c *Core) VerifyEmail(ctx context.Context, token string) (User, error) {
+	usr, err := c.consumeToken(ctx, token, PurposeVerifyEmail)
+	if err != nil {
+		return User{}, err
+	}
+
+	usr.EmailVerified = true
+
+	return c.update(ctx, usr)
+}
+
+// PurgeTokens removes the expired tokens and returns how many were removed.
+func (c *Core) PurgeTokens(ctx context.Context) (int, error) {
+	n, err := c.storer.PurgeTokens(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("purgetokens: %w", err)
+	}
+
+	return n, nil
+}
+
+// consumeToken removes the token so it can't be used again and returns the
+// active user it was issued to. The other tokens issued to the user for the
+// same purpose are removed as well.
+func (c *Core) consumeToken(ctx context.Context, token string, purpose Purpose) (User, error) {
+	tkn, err := c.storer.ConsumeToken(ctx, hashToken(token), purpose)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, ErrInvalidToken
+		}
+		return User{}, fmt.Errorf("consumetoken: %w", err)
+	}
+
+	usr, err := c.tokenUser(ctx, tkn)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := c.storer.DeleteTokens(ctx, usr.ID, purpose); err != nil {
+		return User{}, fmt.Errorf("deletetokens: %w", err)
+	}
+
+	return usr, nil
+}
+
+// tokenUser returns the active user the token was issued to, as long as the
+// token hasn't expired.
+func (c *Core) tokenUser(ctx context.Context, tkn Token) (User, error) {
+	if time.Now().After(tkn.DateExpires) {
+		return User{}, ErrInvalidToken
+	}
+
+	usr, err := c.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, ErrInvalidToken
+		}
+		return User{}, err
+	}
+
+	if err := CheckActive(usr); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// hashToken returns the form of the token that is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
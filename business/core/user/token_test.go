@@ -0,0 +1,200 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/islamghany/service/business/data/order"
+	"github.com/islamghany/service/business/data/transaction"
+)
+
+// memStore keeps the users and tokens in memory for the tests.
+type memStore struct {
+	users  map[uuid.UUID]User
+	tokens map[string]Token
+}
+
+func newMemStore(users ...User) *memStore {
+	s := memStore{
+		users:  make(map[uuid.UUID]User),
+		tokens: make(map[string]Token),
+	}
+
+	for _, usr := range users {
+		s.users[usr.ID] = usr
+	}
+
+	return &s
+}
+
+func (s *memStore) ExecuteUnderTransaction(tx transaction.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *memStore) Create(ctx context.Context, usr User) error {
+	s.users[usr.ID] = usr
+	return nil
+}
+
+func (s *memStore) Update(ctx context.Context, usr User) error {
+	stored, ok := s.users[usr.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	usr.FailedLogins = stored.FailedLogins
+	usr.LockedUntil = stored.LockedUntil
+	s.users[usr.ID] = usr
+
+	return nil
+}
+
+func (s *memStore) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *memStore) CreateToken(ctx context.Context, tkn Token) error {
+	s.tokens[tkn.Hash] = tkn
+	return nil
+}
+
+func (s *memStore) QueryToken(ctx context.Context, hash string, purpose Purpose) (Token, error) {
+	tkn, ok := s.tokens[hash]
+	if !ok || tkn.Purpose != purpose {
+		return Token{}, ErrNotFound
+	}
+
+	return tkn, nil
+}
+
+func (s *memStore) ConsumeToken(ctx context.Context, hash string, purpose Purpose) (Token, error) {
+	tkn, err := s.QueryToken(ctx, hash, purpose)
+	if err != nil {
+		return Token{}, err
+	}
+
+	delete(s.tokens, hash)
+
+	return tkn, nil
+}
+
+func (s *memStore) DeleteTokens(ctx context.Context, userID uuid.UUID, purpose Purpose) error {
+	for hash, tkn := range s.tokens {
+		if tkn.UserID == userID && tkn.Purpose == purpose {
+			delete(s.tokens, hash)
+		}
+	}
+
+	return nil
+}
+
+func (s *memStore) PurgeTokens(ctx context.Context, expiredBefore time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *memStore) RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error) {
+	usr, ok := s.users[userID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	usr.FailedLogins++
+	s.users[userID] = usr
+
+	return usr.FailedLogins, nil
+}
+
+func (s *memStore) LockUntil(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	usr := s.users[userID]
+	usr.LockedUntil = until
+	s.users[userID] = usr
+
+	return nil
+}
+
+func (s *memStore) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	usr := s.users[userID]
+	usr.FailedLogins = 0
+	usr.LockedUntil = time.Time{}
+	s.users[userID] = usr
+
+	return nil
+}
+
+func (s *memStore) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error) {
+	return nil, nil
+}
+
+func (s *memStore) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return len(s.users), nil
+}
+
+func (s *memStore) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	usr, ok := s.users[userID]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	return usr, nil
+}
+
+func (s *memStore) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	for _, usr := range s.users {
+		if usr.Email.Address == email.Address {
+			return usr, nil
+		}
+	}
+
+	return User{}, ErrNotFound
+}
+
+// =============================================================================
+
+func TestVerifyEmailAfterUpdate(t *testing.T) {
+	tt := []struct {
+		name  string
+		email string
+		err   error
+	}{
+		{name: "same email", email: "bill@example.com", err: nil},
+		{name: "new email", email: "ed@example.com", err: ErrInvalidToken},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			usr := User{
+				ID:      uuid.New(),
+				Name:    "Bill Kennedy",
+				Email:   mail.Address{Address: "bill@example.com"},
+				Enabled: true,
+			}
+			c := Core{storer: newMemStore(usr)}
+
+			token, err := c.IssueToken(ctx, usr, PurposeVerifyEmail, time.Hour)
+			if err != nil {
+				t.Fatalf("issuing token: %v", err)
+			}
+
+			email := mail.Address{Address: tst.email}
+			usr, err = c.Update(ctx, usr, UpdateUser{Email: &email})
+			if err != nil {
+				t.Fatalf("updating: %v", err)
+			}
+
+			usr, err = c.VerifyEmail(ctx, token)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("got %v, exp %v", err, tst.err)
+			}
+
+			if tst.err == nil && !usr.EmailVerified {
+				t.Error("got an unverified email, exp it verified")
+			}
+		})
+	}
+}
@@ -2,20 +2,42 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/mail"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/islamghany/service/business/data/order"
+	"github.com/islamghany/service/business/data/transaction"
 	"github.com/islamghany/service/foundation/logger"
-	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound              = errors.New("user not found")
+	ErrUniqueEmail           = errors.New("email is not unique")
+	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrDisabled              = errors.New("user is disabled")
+	ErrDeleted               = errors.New("user is deleted")
+	ErrLocked                = errors.New("user is locked out, try again later")
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
+	ExecuteUnderTransaction(tx transaction.CommitRollbacker) (Storer, error)
 	Create(ctx context.Context, usr User) error
+	Update(ctx context.Context, usr User) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	CreateToken(ctx context.Context, tkn Token) error
+	QueryToken(ctx context.Context, hash string, purpose Purpose) (Token, error)
+	ConsumeToken(ctx context.Context, hash string, purpose Purpose) (Token, error)
+	DeleteTokens(ctx context.Context, userID uuid.UUID, purpose Purpose) error
+	PurgeTokens(ctx context.Context, expiredBefore time.Time) (int, error)
+	RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error)
+	LockUntil(ctx context.Context, userID uuid.UUID, until time.Time) error
+	ResetFailedLogins(ctx context.Context, userID uuid.UUID) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error)
 	Count(ctx context.Context, filter QueryFilter) (int, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
@@ -26,23 +48,46 @@ type Storer interface {
 
 // Core manages the set of APIs for user access.
 type Core struct {
-	storer Storer
-	log    *logger.Logger
+	storer    Storer
+	log       *logger.Logger
+	passwords PasswordConfig
 }
 
-// NewCore constructs a core for user api access.
-func NewCore(log *logger.Logger, storer Storer) *Core {
+// NewCore constructs a core for user api access. The passwords
+// configuration decides which passwords are accepted and how they are
+// hashed.
+func NewCore(log *logger.Logger, storer Storer, passwords PasswordConfig) *Core {
 	return &Core{
-		storer: storer,
-		log:    log,
+		storer:    storer,
+		log:       log,
+		passwords: passwords.withDefaults(),
 	}
 }
 
+// ExecuteUnderTransaction constructs a new Core value that will use the
+// specified transaction in any store related calls.
+func (c *Core) ExecuteUnderTransaction(tx transaction.CommitRollbacker) (*Core, error) {
+	storer, err := c.storer.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Core{
+		storer:    storer,
+		log:       c.log,
+		passwords: c.passwords,
+	}, nil
+}
+
 // Create adds a new user to the system.
 func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err := c.passwords.checkPassword(nu.Password, nu.Name, nu.Email); err != nil {
+		return User{}, fmt.Errorf("checkpassword: %w", err)
+	}
+
+	hash, err := c.passwords.hashPassword(nu.Password)
 	if err != nil {
-		return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		return User{}, fmt.Errorf("hashpassword: %w", err)
 	}
 
 	now := time.Now()
@@ -65,3 +110,236 @@ func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
 
 	return usr, nil
 }
+
+// Update modifies information about a user. A new email is no longer
+// verified and the verification tokens sent to the old one stop working, so
+// Update should run under a transaction when the email changes.
+func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+
+	if uu.Email != nil {
+		if uu.Email.Address != usr.Email.Address {
+			if err := c.storer.DeleteTokens(ctx, usr.ID, PurposeVerifyEmail); err != nil {
+				return User{}, fmt.Errorf("deletetokens: %w", err)
+			}
+			usr.EmailVerified = false
+		}
+		usr.Email = *uu.Email
+	}
+
+	if uu.Roles != nil {
+		usr.Roles = uu.Roles
+	}
+
+	if uu.Password != nil {
+		if err := c.passwords.checkPassword(*uu.Password, usr.Name, usr.Email); err != nil {
+			return User{}, fmt.Errorf("checkpassword: %w", err)
+		}
+
+		pw, err := c.passwords.hashPassword(*uu.Password)
+		if err != nil {
+			return User{}, fmt.Errorf("hashpassword: %w", err)
+		}
+		usr.PasswordHash = pw
+	}
+
+	if uu.Department != nil {
+		usr.Department = *uu.Department
+	}
+
+	if uu.Enabled != nil {
+		usr.Enabled = *uu.Enabled
+	}
+
+	return c.update(ctx, usr)
+}
+
+// Disable prevents the user from signing in and being issued tokens.
+func (c *Core) Disable(ctx context.Context, usr User) (User, error) {
+	usr.Enabled = false
+	return c.update(ctx, usr)
+}
+
+// Enable allows a disabled user to sign in again.
+func (c *Core) Enable(ctx context.Context, usr User) (User, error) {
+	usr.Enabled = true
+	return c.update(ctx, usr)
+}
+
+// Delete marks the user as deleted. The user is left out of queries and can
+// be restored until it's purged.
+func (c *Core) Delete(ctx context.Context, usr User) (User, error) {
+	if !usr.DateDeleted.IsZero() {
+		return usr, nil
+	}
+
+	usr.DateDeleted = time.Now()
+	return c.update(ctx, usr)
+}
+
+// Restore brings back a deleted user that wasn't purged yet.
+func (c *Core) Restore(ctx context.Context, usr User) (User, error) {
+	usr.DateDeleted = time.Time{}
+	return c.update(ctx, usr)
+}
+
+// Purge permanently removes the users deleted longer ago than the retention
+// and returns how many were removed.
+func (c *Core) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	n, err := c.storer.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
+// Authenticate finds a user by their email and verifies their password. On
+// success it returns a User representing this user. Deleted users are
+// reported as unknown and disabled users are refused once their password
+// is verified. When the stored hash was made with another algorithm or
+// weaker parameters than the configured ones, the password is hashed again
+// and stored.
+//
+// Failed logins are counted and lock the user out once they reach the
+// lockout threshold, refusing any password with ErrLocked until the lockout
+// expires. The password is still compared so a locked out user takes as
+// long to refuse, and callers must not tell ErrLocked apart from
+// ErrAuthenticationFailure or the lockout reveals the account exists.
+// Authenticate must not run in a transaction that is rolled back on failure
+// or the failed logins are lost.
+func (c *Core) Authenticate(ctx context.Context, email mail.Address, password string) (User, error) {
+	usr, err := c.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, ErrAuthenticationFailure
+		}
+		return User{}, err
+	}
+
+	if !usr.DateDeleted.IsZero() {
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if time.Now().Before(usr.LockedUntil) {
+		comparePassword(usr.PasswordHash, password)
+		return User{}, ErrLocked
+	}
+
+	if err := comparePassword(usr.PasswordHash, password); err != nil {
+		if err := c.recordFailedLogin(ctx, usr); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if err := CheckActive(usr); err != nil {
+		return User{}, err
+	}
+
+	if usr.FailedLogins > 0 || !usr.LockedUntil.IsZero() {
+		if err := c.storer.ResetFailedLogins(ctx, usr.ID); err != nil {
+			return User{}, fmt.Errorf("resetfailedlogins: %w", err)
+		}
+		usr.FailedLogins = 0
+		usr.LockedUntil = time.Time{}
+	}
+
+	if c.passwords.needsRehash(usr.PasswordHash) {
+		usr = c.rehash(ctx, usr, password)
+	}
+
+	return usr, nil
+}
+
+// rehash stores the password hashed with the configured algorithm and
+// parameters. A failure is only logged since the user was authenticated
+// and the old hash keeps working.
+func (c *Core) rehash(ctx context.Context, usr User, password string) User {
+	hash, err := c.passwords.hashPassword(password)
+	if err != nil {
+		c.log.Error(ctx, "rehash password", "userID", usr.ID, "msg", err)
+		return usr
+	}
+
+	upd := usr
+	upd.PasswordHash = hash
+
+	upd, err = c.update(ctx, upd)
+	if err != nil {
+		c.log.Error(ctx, "rehash password", "userID", usr.ID, "msg", err)
+		return usr
+	}
+
+	c.log.Info(ctx, "rehash password", "userID", usr.ID, "algorithm", c.passwords.Algorithm)
+
+	return upd
+}
+
+// CheckActive returns ErrDeleted or ErrDisabled when the user must not sign
+// in or be issued a token.
+func CheckActive(usr User) error {
+	switch {
+	case !usr.DateDeleted.IsZero():
+		return ErrDeleted
+	case !usr.Enabled:
+		return ErrDisabled
+	}
+
+	return nil
+}
+
+func (c *Core) update(ctx context.Context, usr User) (User, error) {
+	usr.DateUpdated = time.Now()
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Query retrieves a list of existing users.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	users, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the total number of users.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	return c.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the user by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	user, err := c.storer.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return user, nil
+}
+
+// QueryByEmail finds the user by a specified user email.
+func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	user, err := c.storer.QueryByEmail(ctx, email)
+	if err != nil {
+		return User{}, fmt.Errorf("query: email[%s]: %w", email.Address, err)
+	}
+
+	return user, nil
+}
@@ -6,11 +6,11 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/ardanlabs/darwin/v3"
-	"github.com/ardanlabs/darwin/v3/dialects/postgres"
-	"github.com/ardanlabs/darwin/v3/drivers/generic"
-	database "github.com/ardanlabs/service/business/data/dbsql/pgx"
+	database "github.com/islamghany/service/business/data/dbsql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -22,20 +22,248 @@ var (
 	seedDoc string
 )
 
+// Set of error variables for migrations.
+var (
+	ErrChecksumDrift = errors.New("applied migration changed")
+	ErrNoDown        = errors.New("migration has no down script")
+)
+
+// Status describes a migration and whether it was applied to the database.
+// Drift is set when the script changed since it was applied and Missing is
+// set when an applied version no longer exists in the document.
+type Status struct {
+	Version     float64
+	Description string
+	Checksum    string
+	Applied     bool
+	AppliedAt   time.Time
+	Drift       bool
+	Missing     bool
+}
+
 // Migrate attempts to bring the database up to date with the migrations
-// defined in this package.
+// defined in this package. An advisory lock is held for the duration so
+// concurrent instances apply the migrations once. Migrating is refused when
+// an applied migration changed.
 func Migrate(ctx context.Context, db *sqlx.DB) error {
+	return withLock(ctx, db, func(conn *sqlx.Conn) error {
+		if err := ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		pending, err := plan(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending {
+			if err := apply(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Plan returns the migrations Migrate would apply, in order, without applying
+// them.
+func Plan(ctx context.Context, db *sqlx.DB) ([]Migration, error) {
+	var pending []Migration
+	err := withLock(ctx, db, func(conn *sqlx.Conn) error {
+		var err error
+		pending, err = plan(ctx, conn)
+		return err
+	})
+
+	return pending, err
+}
+
+// Rollback reverts the applied migrations newer than the specified version,
+// newest first, using their down scripts. Nothing is reverted if one of them
+// has no down script.
+func Rollback(ctx context.Context, db *sqlx.DB, version float64) error {
+	return withLock(ctx, db, func(conn *sqlx.Conn) error {
+		if err := ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		undo, err := planRollback(ctx, conn, version)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range undo {
+			if err := revert(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PlanRollback returns the migrations Rollback would revert, in order,
+// without reverting them.
+func PlanRollback(ctx context.Context, db *sqlx.DB, version float64) ([]Migration, error) {
+	var undo []Migration
+	err := withLock(ctx, db, func(conn *sqlx.Conn) error {
+		var err error
+		undo, err = planRollback(ctx, conn, version)
+		return err
+	})
+
+	return undo, err
+}
+
+// MigrationStatus returns every migration with its state in the database,
+// ordered by version.
+func MigrationStatus(ctx context.Context, db *sqlx.DB) ([]Status, error) {
+	var statuses []Status
+	err := withLock(ctx, db, func(conn *sqlx.Conn) error {
+		migs, done, err := load(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[float64]bool, len(migs))
+		for _, mig := range migs {
+			known[mig.Version] = true
+
+			st := Status{
+				Version:     mig.Version,
+				Description: mig.Description,
+				Checksum:    mig.Checksum(),
+			}
+
+			if r, exists := done[mig.Version]; exists {
+				st.Applied = true
+				st.AppliedAt = time.Unix(r.AppliedAt, 0)
+				st.Drift = r.Checksum != st.Checksum
+			}
+
+			statuses = append(statuses, st)
+		}
+
+		for _, r := range done {
+			if known[r.Version] {
+				continue
+			}
+
+			statuses = append(statuses, Status{
+				Version:     r.Version,
+				Description: r.Description,
+				Checksum:    r.Checksum,
+				Applied:     true,
+				AppliedAt:   time.Unix(r.AppliedAt, 0),
+				Missing:     true,
+			})
+		}
+
+		return nil
+	})
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, err
+}
+
+// =============================================================================
+
+// withLock checks the database is reachable and runs fn while holding the
+// migration advisory lock.
+func withLock(ctx context.Context, db *sqlx.DB, fn func(conn *sqlx.Conn) error) (err error) {
 	if err := database.StatusCheck(ctx, db); err != nil {
 		return fmt.Errorf("status check database: %w", err)
 	}
 
-	driver, err := generic.New(db.DB, postgres.Dialect{})
+	conn, err := lock(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if errUnlock := unlock(conn); errUnlock != nil && err == nil {
+			err = errUnlock
+		}
+	}()
+
+	return fn(conn)
+}
+
+// load parses the document and reads the applied versions.
+func load(ctx context.Context, conn *sqlx.Conn) ([]Migration, map[float64]record, error) {
+	migs, err := parseMigrations(migrateDoc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse migrations: %w", err)
+	}
+
+	done, err := applied(ctx, conn)
 	if err != nil {
-		return fmt.Errorf("construct darwin driver: %w", err)
+		return nil, nil, err
+	}
+
+	return migs, done, nil
+}
+
+// plan returns the pending migrations after making sure none of the applied
+// ones changed.
+func plan(ctx context.Context, conn *sqlx.Conn) ([]Migration, error) {
+	migs, done, err := load(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifted []string
+	var pending []Migration
+	for _, mig := range migs {
+		r, exists := done[mig.Version]
+		if !exists {
+			pending = append(pending, mig)
+			continue
+		}
+
+		if r.Checksum != mig.Checksum() {
+			drifted = append(drifted, fmt.Sprint(mig.Version))
+		}
+	}
+
+	if len(drifted) > 0 {
+		return nil, fmt.Errorf("versions %s: %w", strings.Join(drifted, ", "), ErrChecksumDrift)
+	}
+
+	return pending, nil
+}
+
+// planRollback returns the applied migrations newer than the version, newest
+// first.
+func planRollback(ctx context.Context, conn *sqlx.Conn, version float64) ([]Migration, error) {
+	migs, done, err := load(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var undo []Migration
+	for i := len(migs) - 1; i >= 0; i-- {
+		mig := migs[i]
+		if mig.Version <= version {
+			break
+		}
+
+		if _, exists := done[mig.Version]; !exists {
+			continue
+		}
+
+		if mig.Down == "" {
+			return nil, fmt.Errorf("version %v: %w", mig.Version, ErrNoDown)
+		}
+
+		undo = append(undo, mig)
 	}
 
-	d := darwin.New(driver, darwin.ParseMigrations(migrateDoc))
-	return d.Migrate()
+	return undo, nil
 }
 
 // Seed runs the seed document defined in this package against db. The queries
@@ -0,0 +1,261 @@
+package dbmigrate
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration represents a single versioned script from the migrate document.
+// The Down script reverts the Up script and is optional, but a migration
+// without one can't be rolled back.
+type Migration struct {
+	Version     float64
+	Description string
+	Up          string
+	Down        string
+}
+
+// Checksum returns the md5 of the up script, used to detect a script that
+// was changed after it was applied.
+func (m Migration) Checksum() string {
+	sum := md5.Sum([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseMigrations splits the document into migrations. Every migration starts
+// with a "-- Version:" comment and may have a "-- Description:" comment. The
+// lines following a "-- Down" comment make up the down script.
+func parseMigrations(doc string) ([]Migration, error) {
+	var migs []Migration
+	var cur *Migration
+	var up, down strings.Builder
+	var inDown bool
+
+	flush := func() {
+		if cur != nil {
+			cur.Up = strings.TrimSpace(up.String())
+			cur.Down = strings.TrimSpace(down.String())
+			migs = append(migs, *cur)
+		}
+		up.Reset()
+		down.Reset()
+		inDown = false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(doc))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "-- Version:"):
+			flush()
+
+			v := strings.TrimSpace(strings.TrimPrefix(line, "-- Version:"))
+			version, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing version %q: %w", v, err)
+			}
+			cur = &Migration{Version: version}
+
+		case strings.HasPrefix(line, "-- Description:"):
+			if cur != nil {
+				cur.Description = strings.TrimSpace(strings.TrimPrefix(line, "-- Description:"))
+			}
+
+		case strings.TrimSpace(line) == "-- Down":
+			inDown = true
+
+		case inDown:
+			down.WriteString(line)
+			down.WriteString("\n")
+
+		default:
+			up.WriteString(line)
+			up.WriteString("\n")
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning: %w", err)
+	}
+
+	sort.SliceStable(migs, func(i, j int) bool {
+		return migs[i].Version < migs[j].Version
+	})
+
+	for i := 1; i < len(migs); i++ {
+		if migs[i].Version == migs[i-1].Version {
+			return nil, fmt.Errorf("duplicate version %v", migs[i].Version)
+		}
+	}
+
+	return migs, nil
+}
+
+// =============================================================================
+
+// lockID identifies the advisory lock held while migrating so only one
+// instance changes the schema at a time.
+const lockID = 7_319_483_201
+
+// The table layout is the one used by darwin so databases migrated by it
+// keep working.
+const createTable = `
+CREATE TABLE IF NOT EXISTS darwin_migrations (
+	id             SERIAL                  NOT NULL,
+	version        REAL                    NOT NULL,
+	description    CHARACTER VARYING (255) NOT NULL,
+	checksum       CHARACTER VARYING (32)  NOT NULL,
+	applied_at     INTEGER                 NOT NULL,
+	execution_time REAL                    NOT NULL,
+	UNIQUE         (version),
+	PRIMARY KEY    (id)
+);`
+
+// record represents a row of the migrations table. The version is stored as
+// a REAL, so it's converted through numeric to compare equal to the version
+// parsed from the document.
+type record struct {
+	Version     float64 `db:"version"`
+	Description string  `db:"description"`
+	Checksum    string  `db:"checksum"`
+	AppliedAt   int64   `db:"applied_at"`
+}
+
+// lock acquires the advisory lock on a dedicated connection, waiting for any
+// other instance to finish. The returned connection must be used for the
+// work and released with unlock.
+func lock(ctx context.Context, db *sqlx.DB) (*sqlx.Conn, error) {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("conn: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("advisory lock: %w", err)
+	}
+
+	return conn, nil
+}
+
+// unlock releases the advisory lock and the connection. A fresh context is
+// used so the lock is released even when the work was canceled.
+func unlock(conn *sqlx.Conn) error {
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+		return fmt.Errorf("advisory unlock: %w", err)
+	}
+
+	return nil
+}
+
+// ensureTable creates the migrations table if it doesn't exist yet. Only the
+// commands changing the schema call it.
+func ensureTable(ctx context.Context, conn *sqlx.Conn) error {
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// applied returns the rows of the migrations table keyed by version. A
+// missing table means nothing was applied yet.
+func applied(ctx context.Context, conn *sqlx.Conn) (map[float64]record, error) {
+	var exists bool
+	if err := conn.GetContext(ctx, &exists, `SELECT to_regclass('darwin_migrations') IS NOT NULL`); err != nil {
+		return nil, fmt.Errorf("check migrations table: %w", err)
+	}
+
+	if !exists {
+		return map[float64]record{}, nil
+	}
+
+	const q = `
+	SELECT
+		version::numeric::float8 AS version, description, checksum, applied_at
+	FROM
+		darwin_migrations`
+
+	var records []record
+	if err := conn.SelectContext(ctx, &records, q); err != nil {
+		return nil, fmt.Errorf("select versions: %w", err)
+	}
+
+	m := make(map[float64]record, len(records))
+	for _, r := range records {
+		m[r.Version] = r
+	}
+
+	return m, nil
+}
+
+// apply runs the up script and records the version in a single transaction.
+func apply(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return fmt.Errorf("exec version %v: %w", mig.Version, err)
+	}
+
+	const q = `
+	INSERT INTO darwin_migrations
+		(version, description, checksum, applied_at, execution_time)
+	VALUES
+		($1, $2, $3, $4, $5)`
+
+	if _, err := tx.ExecContext(ctx, q, mig.Version, mig.Description, mig.Checksum(), time.Now().Unix(), time.Since(start).Seconds()); err != nil {
+		return fmt.Errorf("record version %v: %w", mig.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// revert runs the down script and removes the version in a single
+// transaction.
+func revert(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+		return fmt.Errorf("exec down version %v: %w", mig.Version, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM darwin_migrations WHERE version = $1::real`, mig.Version); err != nil {
+		return fmt.Errorf("remove version %v: %w", mig.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
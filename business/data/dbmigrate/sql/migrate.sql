@@ -11,4 +11,38 @@ CREATE TABLE users (
     date_created TIMESTAMP NOT NULL,
     date_updated TIMESTAMP NOT NULL,
     PRIMARY KEY (user_id)
-);
\ No newline at end of file
+);
+-- Down
+DROP TABLE users;
+
+-- Version: 1.02
+-- Description: Add soft delete to users
+ALTER TABLE users ADD COLUMN date_deleted TIMESTAMP NULL;
+CREATE INDEX users_date_deleted_idx ON users (date_deleted) WHERE date_deleted IS NOT NULL;
+-- Down
+DROP INDEX users_date_deleted_idx;
+ALTER TABLE users DROP COLUMN date_deleted;
+
+-- Version: 1.03
+-- Description: Add email verification and user tokens
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE user_tokens (
+    token_hash TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    date_expires TIMESTAMP NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    PRIMARY KEY (token_hash)
+);
+CREATE INDEX user_tokens_user_id_idx ON user_tokens (user_id, purpose);
+-- Down
+DROP TABLE user_tokens;
+ALTER TABLE users DROP COLUMN email_verified;
+
+-- Version: 1.04
+-- Description: Add login lockout to users
+ALTER TABLE users ADD COLUMN failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP NULL;
+-- Down
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_logins;
@@ -0,0 +1,367 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ClusterConfig is the required properties to route reads to replicas of the
+// primary database.
+type ClusterConfig struct {
+	// ReplicaHosts are the hosts of the read replicas. The other connection
+	// properties are the ones of the primary.
+	ReplicaHosts []string
+
+	// ReadYourWrites is how long reads keep going to the primary after a
+	// write in the same request, so the request sees its own changes even
+	// when the replicas lag behind.
+	ReadYourWrites time.Duration
+
+	// CheckInterval is how often the replicas are checked.
+	CheckInterval time.Duration
+
+	// FailureThreshold is the number of consecutive failed checks before a
+	// replica stops receiving reads. A single successful check brings it
+	// back.
+	FailureThreshold int
+}
+
+// PoolStats describes the state and usage of a connection pool.
+type PoolStats struct {
+	Host    string `json:"host"`
+	Role    string `json:"role"`
+	Healthy bool   `json:"healthy"`
+	Reads   uint64 `json:"reads"`
+	Writes  uint64 `json:"writes"`
+	sql.DBStats
+}
+
+// Cluster routes queries between a primary database and its read replicas.
+// Writes, transactions and locking reads go to the primary and other reads
+// are spread round-robin over the healthy replicas, falling back to the
+// primary when none are healthy. Cluster implements sqlx.ExtContext so it
+// can be given to the stores in place of a *sqlx.DB.
+type Cluster struct {
+	primary  *pool
+	replicas []*pool
+	cfg      ClusterConfig
+	next     atomic.Uint64
+
+	shutdown  chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenCluster opens the primary database and every replica and starts
+// checking the health of the replicas.
+func OpenCluster(cfg Config, ccfg ClusterConfig) (*Cluster, error) {
+	if ccfg.CheckInterval <= 0 {
+		ccfg.CheckInterval = 5 * time.Second
+	}
+	if ccfg.FailureThreshold <= 0 {
+		ccfg.FailureThreshold = 3
+	}
+
+	primary, err := Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("open primary: %w", err)
+	}
+
+	c := Cluster{
+		primary:  newPool(cfg.Host, "primary", primary),
+		cfg:      ccfg,
+		shutdown: make(chan struct{}),
+	}
+
+	for _, host := range ccfg.ReplicaHosts {
+		rcfg := cfg
+		rcfg.Host = host
+
+		db, err := Open(rcfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("open replica %s: %w", host, err)
+		}
+
+		c.replicas = append(c.replicas, newPool(host, "replica", db))
+	}
+
+	if len(c.replicas) > 0 {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.checkReplicas()
+		}()
+	}
+
+	return &c, nil
+}
+
+// Primary returns the primary database, used for migrations and
+// transactions.
+func (c *Cluster) Primary() *sqlx.DB {
+	return c.primary.db
+}
+
+// Close stops checking the replicas and closes every pool. It's safe to call
+// more than once, the later calls return the result of the first.
+func (c *Cluster) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.shutdown)
+		c.wg.Wait()
+
+		errs := []error{c.primary.db.Close()}
+		for _, r := range c.replicas {
+			errs = append(errs, r.db.Close())
+		}
+
+		c.closeErr = errors.Join(errs...)
+	})
+
+	return c.closeErr
+}
+
+// Stats returns the state and usage of every pool, primary first.
+func (c *Cluster) Stats() []PoolStats {
+	stats := []PoolStats{c.primary.stats()}
+	for _, r := range c.replicas {
+		stats = append(stats, r.stats())
+	}
+
+	return stats
+}
+
+// ReplicaCheck returns an error naming the replicas currently not receiving
+// reads.
+func (c *Cluster) ReplicaCheck(ctx context.Context) error {
+	var ejected []string
+	for _, r := range c.replicas {
+		if !r.healthy.Load() {
+			ejected = append(ejected, r.host)
+		}
+	}
+
+	if len(ejected) > 0 {
+		return fmt.Errorf("replicas ejected: %s", strings.Join(ejected, ", "))
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Cluster must be usable wherever the stores expect a database.
+var _ sqlx.ExtContext = (*Cluster)(nil)
+
+// DriverName returns the driver name of the primary.
+func (c *Cluster) DriverName() string {
+	return c.primary.db.DriverName()
+}
+
+// Rebind transforms the query for the bind type of the primary.
+func (c *Cluster) Rebind(query string) string {
+	return c.primary.db.Rebind(query)
+}
+
+// BindNamed binds the named query for the bind type of the primary.
+func (c *Cluster) BindNamed(query string, arg any) (string, []any, error) {
+	return c.primary.db.BindNamed(query, arg)
+}
+
+// QueryContext runs the query on the pool chosen for it.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.pick(ctx, query).QueryContext(ctx, query, args...)
+}
+
+// QueryxContext runs the query on the pool chosen for it.
+func (c *Cluster) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return c.pick(ctx, query).QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext runs the query on the pool chosen for it.
+func (c *Cluster) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	return c.pick(ctx, query).QueryRowxContext(ctx, query, args...)
+}
+
+// ExecContext runs the statement on the primary.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	markWrite(ctx)
+	c.primary.writes.Add(1)
+
+	return c.primary.db.ExecContext(ctx, query, args...)
+}
+
+// =============================================================================
+
+// pick returns the pool the query should run on.
+func (c *Cluster) pick(ctx context.Context, query string) *sqlx.DB {
+	if !readOnly(query) {
+		markWrite(ctx)
+		c.primary.writes.Add(1)
+		return c.primary.db
+	}
+
+	if len(c.replicas) > 0 && !wroteWithin(ctx, c.cfg.ReadYourWrites) {
+		n := uint64(len(c.replicas))
+		start := c.next.Add(1)
+
+		for i := uint64(0); i < n; i++ {
+			r := c.replicas[(start+i)%n]
+			if r.healthy.Load() {
+				r.reads.Add(1)
+				return r.db
+			}
+		}
+	}
+
+	c.primary.reads.Add(1)
+	return c.primary.db
+}
+
+// checkReplicas checks every replica on an interval until the cluster is
+// closed.
+func (c *Cluster) checkReplicas() {
+	ticker := time.NewTicker(c.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		for _, r := range c.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CheckInterval)
+			err := Ping(ctx, r.db)
+			cancel()
+
+			switch {
+			case err == nil:
+				r.failures = 0
+				r.healthy.Store(true)
+
+			default:
+				r.failures++
+				if r.failures >= c.cfg.FailureThreshold {
+					r.healthy.Store(false)
+				}
+			}
+		}
+	}
+}
+
+// readOnly reports whether the query can be served by a replica. Only SELECT
+// statements and WITH queries qualify, and not when they lock rows (FOR
+// UPDATE, FOR SHARE and their KEY variants), modify data in a CTE or select
+// INTO a table. The check works on the words of the query, so a column or
+// literal using one of those words sends the query to the primary, which is
+// always safe.
+func readOnly(query string) bool {
+	words := strings.FieldsFunc(strings.ToUpper(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_'
+	})
+
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return false
+	}
+
+	for i, w := range words {
+		switch w {
+		case "INSERT", "UPDATE", "DELETE", "MERGE", "INTO":
+			return false
+
+		case "FOR":
+			if i+1 < len(words) {
+				switch words[i+1] {
+				case "SHARE", "NO", "KEY":
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// =============================================================================
+
+// pool is a connection pool to one of the cluster databases.
+type pool struct {
+	host     string
+	role     string
+	db       *sqlx.DB
+	healthy  atomic.Bool
+	reads    atomic.Uint64
+	writes   atomic.Uint64
+	failures int
+}
+
+func newPool(host string, role string, db *sqlx.DB) *pool {
+	p := pool{
+		host: host,
+		role: role,
+		db:   db,
+	}
+	p.healthy.Store(true)
+
+	return &p
+}
+
+func (p *pool) stats() PoolStats {
+	return PoolStats{
+		Host:    p.host,
+		Role:    p.role,
+		Healthy: p.healthy.Load(),
+		Reads:   p.reads.Load(),
+		Writes:  p.writes.Load(),
+		DBStats: p.db.Stats(),
+	}
+}
+
+// =============================================================================
+
+type writesKey int
+
+const wKey writesKey = 1
+
+// writeMark holds the time of the last write made in a request.
+type writeMark struct {
+	at atomic.Int64
+}
+
+// TrackWrites returns a context that records the writes made with it, so
+// reads that follow within the read-your-writes window go to the primary.
+func TrackWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, wKey, &writeMark{})
+}
+
+func markWrite(ctx context.Context) {
+	if m, ok := ctx.Value(wKey).(*writeMark); ok {
+		m.at.Store(time.Now().UnixNano())
+	}
+}
+
+func wroteWithin(ctx context.Context, window time.Duration) bool {
+	m, ok := ctx.Value(wKey).(*writeMark)
+	if !ok {
+		return false
+	}
+
+	at := m.at.Load()
+	if at == 0 {
+		return false
+	}
+
+	return time.Since(time.Unix(0, at)) < window
+}
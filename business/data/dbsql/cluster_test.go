@@ -0,0 +1,34 @@
+package db
+
+import "testing"
+
+func TestReadOnly(t *testing.T) {
+	tt := []struct {
+		name  string
+		query string
+		exp   bool
+	}{
+		{name: "select", query: "SELECT * FROM users WHERE user_id = :user_id", exp: true},
+		{name: "lowercase", query: "\n\tselect * from users", exp: true},
+		{name: "cte", query: "WITH u AS (SELECT * FROM users) SELECT * FROM u", exp: true},
+		{name: "insert", query: "INSERT INTO users (name) VALUES (:name)", exp: false},
+		{name: "for update", query: "SELECT * FROM users FOR UPDATE", exp: false},
+		{name: "for update split", query: "SELECT * FROM users FOR\n\tUPDATE", exp: false},
+		{name: "for share", query: "SELECT * FROM users FOR SHARE", exp: false},
+		{name: "for no key update", query: "SELECT * FROM users FOR NO KEY UPDATE", exp: false},
+		{name: "for key share", query: "SELECT * FROM users FOR KEY SHARE SKIP LOCKED", exp: false},
+		{name: "cte update", query: "WITH u AS (UPDATE users SET name = 'x' RETURNING *) SELECT * FROM u", exp: false},
+		{name: "cte delete", query: "WITH d AS (DELETE FROM tokens RETURNING *) SELECT count(*) FROM d", exp: false},
+		{name: "cte insert", query: "WITH i AS (INSERT INTO users (name) VALUES ('x') RETURNING *) SELECT * FROM i", exp: false},
+		{name: "select into", query: "SELECT * INTO users_copy FROM users", exp: false},
+		{name: "empty", query: "", exp: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := readOnly(tst.query); got != tst.exp {
+				t.Errorf("got %v, exp %v", got, tst.exp)
+			}
+		})
+	}
+}
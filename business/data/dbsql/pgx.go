@@ -3,10 +3,18 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/retry"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -19,14 +27,21 @@ var (
 
 // Config is the required properties to use the database.
 type Config struct {
-	User         string
-	Password     string
-	Host         string
-	Name         string
-	Schema       string
-	MaxIdleConns int
-	MaxOpenConns int
-	DisableTLS   bool
+	User            string
+	Password        string
+	Host            string
+	Name            string
+	Schema          string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// StatementTimeout is set on every connection so the server aborts any
+	// statement running longer, even when the client went away. Zero leaves
+	// the server default.
+	StatementTimeout time.Duration
+	DisableTLS       bool
 }
 
 // Open knows how to open a database connection based on the configuration.
@@ -42,6 +57,9 @@ func Open(cfg Config) (*sqlx.DB, error) {
 	if cfg.Schema != "" {
 		q.Set("search_path", cfg.Schema)
 	}
+	if cfg.StatementTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
 
 	u := url.URL{
 		Scheme:   "postgres",
@@ -57,12 +75,16 @@ func Open(cfg Config) (*sqlx.DB, error) {
 	}
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 }
 
 // StatusCheck returns nil if it can successfully talk to the database. It
-// returns a non-nil error otherwise.
+// returns a non-nil error otherwise. Failed attempts are retried until the
+// context is done, which is one second from now if the context has no
+// deadline.
 func StatusCheck(ctx context.Context, db *sqlx.DB) error {
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
@@ -70,20 +92,20 @@ func StatusCheck(ctx context.Context, db *sqlx.DB) error {
 		defer cancel()
 	}
 
-	var pingError error
-	for attempts := 1; ; attempts++ {
-		pingError = db.Ping()
-		if pingError == nil {
-			break
-		}
-		time.Sleep(time.Duration(attempts) * 100 * time.Millisecond)
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
+	cfg := retry.Config{
+		Initial: 100 * time.Millisecond,
+		Max:     time.Second,
 	}
 
-	if ctx.Err() != nil {
-		return ctx.Err()
+	return retry.Do(ctx, cfg, func(ctx context.Context) error {
+		return Ping(ctx, db)
+	})
+}
+
+// Ping makes a single attempt to talk to the database.
+func Ping(ctx context.Context, db *sqlx.DB) error {
+	if err := db.PingContext(ctx); err != nil {
+		return err
 	}
 
 	// Run a simple query to determine connectivity.
@@ -92,3 +114,185 @@ func StatusCheck(ctx context.Context, db *sqlx.DB) error {
 	var tmp bool
 	return db.QueryRowContext(ctx, q).Scan(&tmp)
 }
+
+// =============================================================================
+
+// Set of Postgres error codes mapped to the package errors.
+const (
+	uniqueViolation = "23505"
+	undefinedTable  = "42P01"
+)
+
+// NamedExecContext is a helper function to execute a CUD operation with
+// logging where field replacement is necessary.
+func NamedExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any) (err error) {
+	defer func() {
+		logQuery(ctx, log, query, data, err)
+	}()
+
+	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
+		return mapError(err)
+	}
+
+	return nil
+}
+
+// NamedQuerySlice is a helper function for executing queries that return a
+// collection of data to be unmarshalled into a slice where field replacement
+// is necessary.
+func NamedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, dest *[]T) (err error) {
+	defer func() {
+		logQuery(ctx, log, query, data, err)
+	}()
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return mapError(err)
+	}
+	defer rows.Close()
+
+	var slice []T
+	for rows.Next() {
+		v := new(T)
+		if err := rows.StructScan(v); err != nil {
+			return err
+		}
+		slice = append(slice, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return mapError(err)
+	}
+
+	*dest = slice
+
+	return nil
+}
+
+// NamedQueryStruct is a helper function for executing queries that return a
+// single value to be unmarshalled into a struct type where field replacement
+// is necessary. ErrDBNotFound is returned when no row matches.
+func NamedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, dest any) (err error) {
+	defer func() {
+		logQuery(ctx, log, query, data, err)
+	}()
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return mapError(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return mapError(err)
+		}
+		return ErrDBNotFound
+	}
+
+	if err := rows.StructScan(dest); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// QueryStruct is a helper function for executing queries with positional
+// arguments that return a single value to be unmarshalled into a struct type.
+// ErrDBNotFound is returned when no row matches.
+func QueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, dest any, args ...any) (err error) {
+	defer func() {
+		logQuery(ctx, log, query, args, err)
+	}()
+
+	if err := db.QueryRowxContext(ctx, query, args...).StructScan(dest); err != nil {
+		return mapError(err)
+	}
+
+	return nil
+}
+
+// mapError converts the driver errors the application needs to act on into
+// the package errors.
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDBNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return ErrDBDuplicatedEntry
+		case undefinedTable:
+			return ErrUndefinedTable
+		}
+	}
+
+	return err
+}
+
+// logQuery writes the query with its bound values at debug level. The record
+// is attributed to the caller of the helper.
+func logQuery(ctx context.Context, log *logger.Logger, query string, data any, err error) {
+	if !log.Enabled(ctx, logger.LevelDebug) {
+		return
+	}
+
+	attrs := []any{"query", queryString(query, data)}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+
+	log.DebugCtx(ctx, 6, "database query", attrs...)
+}
+
+// queryString provides a pretty print version of the query with the values
+// bound in place of the parameters. Strings and byte slices can hold emails,
+// tokens and hashes, so only their length is shown.
+func queryString(query string, data any) string {
+	switch v := data.(type) {
+	case nil:
+	case []any:
+		// Replace the highest positions first so $1 doesn't match $10.
+		for i := len(v); i > 0; i-- {
+			query = strings.ReplaceAll(query, fmt.Sprintf("$%d", i), queryValue(v[i-1]))
+		}
+	default:
+		named, params, err := sqlx.Named(query, data)
+		if err != nil {
+			return err.Error()
+		}
+
+		query = named
+		for _, param := range params {
+			query = strings.Replace(query, "?", queryValue(param), 1)
+		}
+	}
+
+	query = strings.ReplaceAll(query, "\t", "")
+	query = strings.ReplaceAll(query, "\n", " ")
+
+	return strings.TrimSpace(query)
+}
+
+func queryValue(param any) string {
+	if valuer, ok := param.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err.Error()
+		}
+		param = v
+	}
+
+	switch v := param.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("'[%d bytes]'", len(v))
+	case []byte:
+		return fmt.Sprintf("'[%d bytes]'", len(v))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/islamghany/service/business/data/transaction"
+	"github.com/jmoiron/sqlx"
+)
+
+// TxManager begins transactions on the database.
+type TxManager struct {
+	db *sqlx.DB
+}
+
+// NewTxManager constructs a transaction manager for the database.
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+// Begin starts a transaction bound to the context.
+func (m *TxManager) Begin(ctx context.Context) (transaction.CommitRollbacker, error) {
+	return m.db.BeginTxx(ctx, nil)
+}
+
+// GetExtContext returns the sqlx value the stores use to run queries under
+// the transaction.
+func GetExtContext(tx transaction.CommitRollbacker) (sqlx.ExtContext, error) {
+	ec, ok := tx.(sqlx.ExtContext)
+	if !ok {
+		return nil, fmt.Errorf("transaction value (%T) not of a type *sqlx.Tx", tx)
+	}
+
+	return ec, nil
+}
@@ -0,0 +1,75 @@
+// Package transaction provides support for database transaction handling
+// that spans the web and core layers.
+package transaction
+
+import (
+	"context"
+)
+
+// CommitRollbacker represents a transaction in progress.
+type CommitRollbacker interface {
+	Commit() error
+	Rollback() error
+}
+
+// Beginner represents a value that can begin a transaction.
+type Beginner interface {
+	Begin(ctx context.Context) (CommitRollbacker, error)
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const trKey ctxKey = 1
+
+// state holds the transaction stored in a context and the functions to run
+// once it's committed.
+type state struct {
+	tx    CommitRollbacker
+	after []func()
+}
+
+// Set stores a transaction in the context.
+func Set(ctx context.Context, tx CommitRollbacker) context.Context {
+	return context.WithValue(ctx, trKey, &state{tx: tx})
+}
+
+// Get retrieves the transaction stored in the context, if any.
+func Get(ctx context.Context) (CommitRollbacker, bool) {
+	v, ok := ctx.Value(trKey).(*state)
+	if !ok {
+		return nil, false
+	}
+	return v.tx, true
+}
+
+// AfterCommit registers a function to run once the transaction stored in the
+// context is committed, such as sending an email about the change. The
+// function never runs if the transaction is rolled back. Without a
+// transaction in the context, it runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	v, ok := ctx.Value(trKey).(*state)
+	if !ok {
+		fn()
+		return
+	}
+
+	v.after = append(v.after, fn)
+}
+
+// Committed runs the functions registered with AfterCommit, in order. It's
+// called by whoever started the transaction right after committing it.
+func Committed(ctx context.Context) {
+	v, ok := ctx.Value(trKey).(*state)
+	if !ok {
+		return
+	}
+
+	after := v.after
+	v.after = nil
+
+	for _, fn := range after {
+		fn()
+	}
+}
@@ -0,0 +1,95 @@
+// Package auth provides support for issuing the tokens clients use to call
+// the api.
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Config represents the settings for issuing tokens. The private key used to
+// sign them is read from the <ActiveKID>.pem file in the KeysFolder.
+type Config struct {
+	KeysFolder string
+	ActiveKID  string
+	Issuer     string
+	TTL        time.Duration
+}
+
+// Claims represents the authorization claims transmitted via a JWT.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string
+}
+
+// Auth issues signed tokens.
+type Auth struct {
+	kid    string
+	key    *rsa.PrivateKey
+	method jwt.SigningMethod
+	issuer string
+	ttl    time.Duration
+}
+
+// New constructs an Auth signing with the active key.
+func New(cfg Config) (*Auth, error) {
+	if cfg.ActiveKID == "" {
+		return nil, errors.New("active kid is required")
+	}
+
+	if cfg.TTL <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+
+	pemData, err := os.ReadFile(filepath.Join(cfg.KeysFolder, cfg.ActiveKID+".pem"))
+	if err != nil {
+		return nil, fmt.Errorf("reading private key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	a := Auth{
+		kid:    cfg.ActiveKID,
+		key:    key,
+		method: jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
+		issuer: cfg.Issuer,
+		ttl:    cfg.TTL,
+	}
+
+	return &a, nil
+}
+
+// GenerateToken returns a token for the subject with the roles, valid for
+// the configured ttl.
+func (a *Auth) GenerateToken(subject string, roles []string) (string, error) {
+	now := time.Now().UTC()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    a.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles: roles,
+	}
+
+	token := jwt.NewWithClaims(a.method, claims)
+	token.Header["kid"] = a.kid
+
+	str, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	return str, nil
+}
@@ -0,0 +1,23 @@
+package debug
+
+import (
+	"fmt"
+	"net/http"
+
+	database "github.com/islamghany/service/business/data/dbsql"
+)
+
+// dbStats returns the state and connection pool statistics of every database
+// in the cluster.
+func dbStats(db *database.Cluster) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, fmt.Errorf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, db.Stats(), http.StatusOK)
+	}
+
+	return http.HandlerFunc(h)
+}
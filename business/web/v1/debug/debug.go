@@ -5,13 +5,23 @@ import (
 	"expvar"
 	"net/http"
 	"net/http/pprof"
+
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/logger"
 )
 
+// Config contains all the mandatory systems required by the debug handlers.
+type Config struct {
+	Log  *logger.Logger
+	Logs *logger.RingBuffer // optional
+	DB   *database.Cluster  // optional
+}
+
 // Mux registers all the debug routes from the standard library into a new mux
 // bypassing the use of the DefaultServerMux. Using the DefaultServerMux would
 // be a security risk since a dependency could inject a handler into our service
 // without us knowing it.
-func Mux() *http.ServeMux {
+func Mux(cfg Config) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -20,6 +30,15 @@ func Mux() *http.ServeMux {
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	mux.Handle("/debug/vars/", expvar.Handler())
+	mux.Handle("/debug/loglevel", logLevel(cfg.Log))
+
+	if cfg.Logs != nil {
+		mux.Handle("/debug/logs", logs(cfg.Logs))
+	}
+
+	if cfg.DB != nil {
+		mux.Handle("/debug/db", dbStats(cfg.DB))
+	}
 
 	return mux
 }
@@ -0,0 +1,106 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/islamghany/service/foundation/logger"
+)
+
+// logLevelDocument is the form used to read and change the log level.
+type logLevelDocument struct {
+	Level    string   `json:"level"`
+	TTL      string   `json:"ttl,omitempty"`
+	Previous string   `json:"previous,omitempty"`
+	Sinks    []string `json:"sinks,omitempty"`
+}
+
+// logLevel returns a handler that reports the current minimum log level on GET
+// and changes it on PUT. The level each sink is actually writing at is
+// reported alongside, since sinks use their own level while the logger is at
+// its configured one. When a ttl is provided on PUT, the previous level is
+// restored once the ttl expires.
+//
+//	curl -X PUT localhost:4000/debug/loglevel -d '{"level":"DEBUG","ttl":"10m"}'
+func logLevel(log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, logLevelDocument{Level: log.Level().String(), Sinks: sinkLevels(log)}, http.StatusOK)
+
+		case http.MethodPut:
+			var doc logLevelDocument
+			if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+				writeError(w, fmt.Errorf("decode: %w", err), http.StatusBadRequest)
+				return
+			}
+
+			level, err := logger.ParseLevel(doc.Level)
+			if err != nil {
+				writeError(w, err, http.StatusBadRequest)
+				return
+			}
+
+			previous := log.Level()
+
+			switch doc.TTL {
+			case "":
+				log.SetLevel(level)
+
+			default:
+				ttl, err := time.ParseDuration(doc.TTL)
+				if err != nil || ttl <= 0 {
+					writeError(w, fmt.Errorf("invalid ttl %q", doc.TTL), http.StatusBadRequest)
+					return
+				}
+				previous = log.SetLevelFor(level, ttl)
+				doc.TTL = ttl.String()
+			}
+
+			resp := logLevelDocument{
+				Level:    level.String(),
+				TTL:      doc.TTL,
+				Previous: previous.String(),
+				Sinks:    sinkLevels(log),
+			}
+
+			writeJSON(w, resp, http.StatusOK)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeError(w, fmt.Errorf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// =============================================================================
+
+// sinkLevels returns the names of the levels the sinks are writing at.
+func sinkLevels(log *logger.Logger) []string {
+	levels := log.SinkLevels()
+
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = level.String()
+	}
+
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, data any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, err error, status int) {
+	doc := struct {
+		Error string `json:"error"`
+	}{
+		Error: err.Error(),
+	}
+
+	writeJSON(w, doc, status)
+}
@@ -0,0 +1,100 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/islamghany/service/foundation/logger"
+)
+
+// logs returns a handler that writes the records kept in the ring buffer as
+// JSONL, oldest first. The records can be filtered with the query parameters:
+//
+//	level:    minimum level, such as WARN
+//	trace_id: exact trace ID
+//	since:    RFC3339 time or a duration back from now, such as 5m
+//	until:    RFC3339 time or a duration back from now
+//
+//	curl "localhost:4000/debug/logs?level=ERROR&since=15m"
+func logs(rb *logger.RingBuffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			writeError(w, fmt.Errorf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		filter, err := parseLogsFilter(r, time.Now())
+		if err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+
+		enc := json.NewEncoder(w)
+		for _, rec := range rb.Records(filter) {
+			doc := make(map[string]any, len(rec.Attributes)+3)
+			for k, v := range rec.Attributes {
+				doc[k] = v
+			}
+			doc["time"] = rec.Time
+			doc["level"] = rec.Level.String()
+			doc["msg"] = rec.Message
+
+			if err := enc.Encode(doc); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLogsFilter constructs the ring buffer filter from the query string.
+func parseLogsFilter(r *http.Request, now time.Time) (logger.RingFilter, error) {
+	values := r.URL.Query()
+
+	filter := logger.RingFilter{
+		MinLevel: logger.LevelDebug,
+		TraceID:  values.Get("trace_id"),
+	}
+
+	if v := values.Get("level"); v != "" {
+		level, err := logger.ParseLevel(v)
+		if err != nil {
+			return logger.RingFilter{}, err
+		}
+		filter.MinLevel = level
+	}
+
+	var err error
+	if filter.Since, err = parseLogsTime(values.Get("since"), now); err != nil {
+		return logger.RingFilter{}, fmt.Errorf("since: %w", err)
+	}
+
+	if filter.Until, err = parseLogsTime(values.Get("until"), now); err != nil {
+		return logger.RingFilter{}, fmt.Errorf("until: %w", err)
+	}
+
+	return filter, nil
+}
+
+// parseLogsTime parses an RFC3339 time or a duration back from now.
+func parseLogsTime(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expecting RFC3339 or a duration", v)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,27 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Deadline bounds the time spent handling a request. Database queries run
+// with the request context, so the ones still in flight when the deadline
+// passes are canceled on the server.
+func Deadline(timeout time.Duration) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
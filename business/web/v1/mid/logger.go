@@ -20,6 +20,10 @@ func Logger(log *logger.Logger) web.Middleware {
 				path = fmt.Sprintf("%s?%s", path, r.URL.RawQuery)
 			}
 
+			// Every record logged while handling the request carries the
+			// route that matched it.
+			ctx = logger.AddAttrs(ctx, "route", web.GetRoute(ctx))
+
 			log.Info(ctx, "request started", "method", r.Method, "path", path,
 				"remoteaddr", r.RemoteAddr)
 
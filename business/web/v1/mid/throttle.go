@@ -0,0 +1,66 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/islamghany/service/business/web/v1/respond"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/throttle"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// Throttle limits how often a client IP can call the handler, for endpoints
+// open to brute force such as the ones checking passwords or tokens.
+//
+// The IP is taken from the connection unless ipHeader names a header, such
+// as X-Forwarded-For or X-Real-IP, that a trusted proxy in front of the
+// service sets. Without one, every client behind a proxy shares the proxy's
+// limit. Only name a header when every request goes through such a proxy,
+// since clients can set it themselves to get a fresh limit.
+func Throttle(log *logger.Logger, limiter *throttle.Limiter, ipHeader string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ip := clientIP(r, ipHeader)
+
+			ok, retryAfter := limiter.Allow(ip)
+			if !ok {
+				log.Warn(ctx, "security event", "event", "ip_throttled", "ip", ip, "path", r.URL.Path, "retryAfter", retryAfter.String())
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return respond.NewError(errors.New("too many attempts, try again later"), http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// clientIP returns the IP of the client making the request. When the
+// header holds a list, as X-Forwarded-For does, the last entry is used since
+// it was added by the trusted proxy. The connection's IP is used when the
+// header isn't set.
+func clientIP(r *http.Request, header string) string {
+	if header != "" {
+		values := strings.Split(r.Header.Get(header), ",")
+		if ip := strings.TrimSpace(values[len(values)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
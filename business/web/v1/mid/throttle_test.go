@@ -0,0 +1,95 @@
+package mid
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/islamghany/service/business/web/v1/respond"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/throttle"
+)
+
+func TestThrottle(t *testing.T) {
+	log := logger.New(io.Discard, logger.LevelInfo, "test", func(ctx context.Context) string { return "" })
+
+	var calls int
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls++
+		return nil
+	}
+
+	h := Throttle(log, throttle.New(2, time.Minute), "")(handler)
+
+	tt := []struct {
+		remoteAddr string
+		status     int
+	}{
+		{remoteAddr: "10.0.0.1:5000", status: http.StatusOK},
+		{remoteAddr: "10.0.0.1:5001", status: http.StatusOK},
+		{remoteAddr: "10.0.0.1:5002", status: http.StatusTooManyRequests},
+		{remoteAddr: "10.0.0.2:5000", status: http.StatusOK},
+	}
+
+	for i, tst := range tt {
+		r := httptest.NewRequest(http.MethodGet, "/v1/users/token", nil)
+		r.RemoteAddr = tst.remoteAddr
+		w := httptest.NewRecorder()
+
+		status := http.StatusOK
+		if err := h(context.Background(), w, r); err != nil {
+			re := respond.GetError(err)
+			if re == nil {
+				t.Fatalf("request %d: got %v, exp a trusted error", i, err)
+			}
+			status = re.Status
+
+			if w.Header().Get("Retry-After") == "" {
+				t.Errorf("request %d: got no Retry-After header", i)
+			}
+		}
+
+		if status != tst.status {
+			t.Errorf("request %d: got status %d, exp %d", i, status, tst.status)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("got %d calls to the handler, exp 3", calls)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tt := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		exp        string
+	}{
+		{name: "connection", remoteAddr: "10.0.0.1:5000", exp: "10.0.0.1"},
+		{name: "connection without port", remoteAddr: "10.0.0.1", exp: "10.0.0.1"},
+		{name: "header ignored", remoteAddr: "10.0.0.1:5000", value: "1.2.3.4", exp: "10.0.0.1"},
+		{name: "header", remoteAddr: "10.0.0.1:5000", header: "X-Real-IP", value: "1.2.3.4", exp: "1.2.3.4"},
+		{name: "forwarded list", remoteAddr: "10.0.0.1:5000", header: "X-Forwarded-For", value: "9.9.9.9, 1.2.3.4", exp: "1.2.3.4"},
+		{name: "header missing", remoteAddr: "10.0.0.1:5000", header: "X-Forwarded-For", exp: "10.0.0.1"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/users/token", nil)
+			r.RemoteAddr = tst.remoteAddr
+			if tst.value != "" {
+				r.Header.Set("X-Forwarded-For", tst.value)
+				r.Header.Set("X-Real-IP", tst.value)
+			}
+
+			if got := clientIP(r, tst.header); got != tst.exp {
+				t.Errorf("got %q, exp %q", got, tst.exp)
+			}
+		})
+	}
+}
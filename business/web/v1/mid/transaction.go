@@ -0,0 +1,116 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/islamghany/service/business/data/transaction"
+	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// BeginCommitRollback starts a transaction for every request that changes
+// data and stores it in the context for the handler to use. The transaction
+// is committed when the handler succeeds with a status below 400 and rolled
+// back otherwise. Requests that only read data run without a transaction.
+// It's applied to the routes that need it, after the middleware rejecting
+// requests such as Throttle, so no transaction is opened for them.
+//
+// The response is buffered and only sent once the transaction is done, so a
+// client never sees a success for a change that failed to commit.
+func BeginCommitRollback(log *logger.Logger, bgn transaction.Beginner) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				return handler(ctx, w, r)
+			}
+
+			tx, err := bgn.Begin(ctx)
+			if err != nil {
+				return fmt.Errorf("begin transaction: %w", err)
+			}
+
+			defer func() {
+				if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+					log.Error(ctx, "rollback transaction", "msg", err)
+				}
+			}()
+
+			ctx = transaction.Set(ctx, tx)
+
+			bw := bufferedWriter{header: make(http.Header)}
+
+			if err := handler(ctx, &bw, r); err != nil {
+				return err
+			}
+
+			if status := web.GetValues(ctx).StatusCode; status < http.StatusBadRequest {
+				if err := tx.Commit(); err != nil {
+					return fmt.Errorf("commit transaction: %w", err)
+				}
+
+				transaction.Committed(ctx)
+			}
+
+			return bw.flush(w)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// bufferedWriter holds a response until the transaction is done.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header returns the headers to be sent with the response.
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+// WriteHeader records the status code of the response.
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+// Write buffers the body of the response.
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+
+	return bw.body.Write(b)
+}
+
+// flush sends the buffered response.
+func (bw *bufferedWriter) flush(w http.ResponseWriter) error {
+	if bw.status == 0 {
+		return nil
+	}
+
+	for key, values := range bw.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(bw.status)
+
+	if _, err := w.Write(bw.body.Bytes()); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/foundation/web"
+)
+
+// TrackWrites records the database writes made while handling a request so
+// the reads that follow see them, even when replicas lag behind.
+func TrackWrites() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return handler(database.TrackWrites(ctx), w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
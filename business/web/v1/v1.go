@@ -2,9 +2,15 @@ package v1
 
 import (
 	"os"
+	"time"
 
+	"github.com/islamghany/service/business/core/user"
+	database "github.com/islamghany/service/business/data/dbsql"
+	"github.com/islamghany/service/business/web/v1/auth"
 	"github.com/islamghany/service/business/web/v1/mid"
+	"github.com/islamghany/service/foundation/health"
 	"github.com/islamghany/service/foundation/logger"
+	"github.com/islamghany/service/foundation/mail"
 	"github.com/islamghany/service/foundation/web"
 )
 
@@ -13,6 +19,38 @@ type APIMuxConfig struct {
 	Build    string
 	Shutdown chan os.Signal
 	Log      *logger.Logger
+	Health   *health.Checker
+	DB       *database.Cluster
+	Auth     *auth.Auth
+	Mailer   mail.Sender
+
+	// Passwords is the password policy and hashing used by the user
+	// handlers.
+	Passwords user.PasswordConfig
+	Accounts  AccountsConfig
+
+	// RequestTimeout bounds the time spent handling a request, including
+	// its database queries. Zero means no bound.
+	RequestTimeout time.Duration
+}
+
+// AccountsConfig configures the password reset and email verification
+// flows.
+type AccountsConfig struct {
+	ResetURL       string
+	VerifyURL      string
+	ResetTokenTTL  time.Duration
+	VerifyTokenTTL time.Duration
+
+	// AttemptsPerIP is how many calls a client IP can make to the account
+	// endpoints within AttemptsWindow.
+	AttemptsPerIP  int
+	AttemptsWindow time.Duration
+
+	// ClientIPHeader is the header holding the client IP, set by a trusted
+	// proxy in front of the service. Empty means the IP of the connection
+	// is used.
+	ClientIPHeader string
 }
 
 // RouteAdder defines behavior that sets the routes to bind for an instance
@@ -23,7 +61,16 @@ type RouteAdder interface {
 
 // APIMux constructs a http.Handler with all application routes defined.
 func APIMux(cfg APIMuxConfig, routeAdder RouteAdder) *web.App {
-	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics())
+	mw := []web.Middleware{mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics()}
+	if cfg.RequestTimeout > 0 {
+		mw = append(mw, mid.Deadline(cfg.RequestTimeout))
+	}
+	if cfg.DB != nil {
+		mw = append(mw, mid.TrackWrites())
+	}
+	mw = append(mw, mid.Panics())
+
+	app := web.NewApp(cfg.Shutdown, mw...)
 
 	routeAdder.Add(app, cfg)
 
@@ -0,0 +1,190 @@
+// Package health provides support for running readiness checks against the
+// dependencies a service relies on.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/islamghany/service/foundation/retry"
+)
+
+// Set of status values reported for a check and for the overall report.
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusShutdown = "shutting down"
+)
+
+// CheckFn is a function that reports the health of a single dependency. It
+// must return a non-nil error when the dependency is not usable.
+type CheckFn func(ctx context.Context) error
+
+// Check represents a single named dependency check. A check with a Retry
+// allowing more than one attempt is retried within its timeout before it's
+// reported as failed, so a single dropped connection doesn't flip readiness.
+type Check struct {
+	Name     string
+	Critical bool
+	Timeout  time.Duration
+	Retry    retry.Config
+	Fn       CheckFn
+}
+
+// Result is the outcome of running a single check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the outcome of running all the registered checks.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether the service should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// =============================================================================
+
+// Checker manages the set of checks used to determine if the service is ready
+// to receive traffic.
+type Checker struct {
+	defaultTimeout time.Duration
+	shutdown       atomic.Bool
+
+	mu     sync.RWMutex
+	checks []Check
+}
+
+// NewChecker constructs a checker. The default timeout is applied to any
+// check registered without its own timeout.
+func NewChecker(defaultTimeout time.Duration) *Checker {
+	return &Checker{
+		defaultTimeout: defaultTimeout,
+	}
+}
+
+// Register adds a check to the set of checks run on every readiness request.
+func (c *Checker) Register(chk Check) {
+	if chk.Timeout <= 0 {
+		chk.Timeout = c.defaultTimeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, chk)
+}
+
+// Shutdown marks the service as not ready. It should be called as soon as
+// shutdown begins so the load balancer stops sending new traffic.
+func (c *Checker) Shutdown() {
+	c.shutdown.Store(true)
+}
+
+// IsShutdown reports whether Shutdown has been called.
+func (c *Checker) IsShutdown() bool {
+	return c.shutdown.Load()
+}
+
+// Run executes all the registered checks concurrently, each bounded by its own
+// timeout, and returns the combined report. Only critical checks can mark the
+// report as failed.
+func (c *Checker) Run(ctx context.Context) Report {
+	if c.IsShutdown() {
+		return Report{
+			Status: StatusShutdown,
+			Checks: []Result{},
+		}
+	}
+
+	c.mu.RLock()
+	checks := make([]Check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(checks))
+
+	for i, chk := range checks {
+		go func(i int, chk Check) {
+			defer wg.Done()
+			results[i] = run(ctx, chk)
+		}(i, chk)
+	}
+
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: results,
+	}
+
+	for _, res := range results {
+		if res.Critical && res.Status != StatusOK {
+			report.Status = StatusFailed
+			break
+		}
+	}
+
+	return report
+}
+
+// run executes a single check, recovering from a panic so one misbehaving
+// check can't take the service down.
+func run(ctx context.Context, chk Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, chk.Timeout)
+	defer cancel()
+
+	res := Result{
+		Name:     chk.Name,
+		Status:   StatusOK,
+		Critical: chk.Critical,
+	}
+
+	start := time.Now()
+
+	// The check runs in its own goroutine so a check that ignores its
+	// context can't hold the readiness request past the timeout.
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errCh <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		if chk.Retry.MaxAttempts > 1 {
+			errCh <- retry.Do(ctx, chk.Retry, chk.Fn)
+			return
+		}
+		errCh <- chk.Fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res.Duration = time.Since(start).String()
+
+	if err != nil {
+		res.Status = StatusFailed
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy represents what happens to a record when the async queue is
+// full.
+type OverflowPolicy string
+
+// Set of overflow policies.
+const (
+	// OverflowBlock makes the caller wait for room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropOldest discards the oldest queued record to make room.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+
+	// OverflowDropNewest discards the record being logged.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// ParseOverflowPolicy parses a policy name such as "block" into an
+// OverflowPolicy.
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(strings.ToLower(name)); p {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown overflow policy %q", name)
+}
+
+// AsyncConfig represents the settings for writing records on a background
+// goroutine instead of the goroutine doing the logging.
+type AsyncConfig struct {
+	QueueSize int
+	Overflow  OverflowPolicy
+}
+
+// droppedRecords counts the records discarded by a full queue. It is
+// published through expvar as "logs_dropped" once an async handler exists.
+var (
+	droppedRecords     *expvar.Int
+	droppedRecordsOnce sync.Once
+)
+
+// =============================================================================
+
+// asyncEntry is a record waiting to be written by the handler it was logged
+// through.
+type asyncEntry struct {
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+}
+
+// asyncQueue is shared by an async handler and every handler derived from it
+// with WithAttrs or WithGroup.
+type asyncQueue struct {
+	entries  chan asyncEntry
+	overflow OverflowPolicy
+	pending  atomic.Int64
+
+	// done is closed to stop the background goroutine. Records logged after
+	// it are written by the goroutine doing the logging. The mutex is held
+	// for reading while pushing so no entry is queued once the goroutine
+	// may have stopped.
+	mu      sync.RWMutex
+	closed  bool
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// asyncHandler queues records for a background goroutine that passes them to
+// the wrapped handler.
+type asyncHandler struct {
+	handler slog.Handler
+	queue   *asyncQueue
+}
+
+func newAsyncHandler(handler slog.Handler, cfg AsyncConfig) *asyncHandler {
+	droppedRecordsOnce.Do(func() {
+		droppedRecords = expvar.NewInt("logs_dropped")
+	})
+
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowBlock
+	}
+
+	q := asyncQueue{
+		entries:  make(chan asyncEntry, cfg.QueueSize),
+		overflow: cfg.Overflow,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	go q.run()
+
+	return &asyncHandler{
+		handler: handler,
+		queue:   &q,
+	}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *asyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// WithAttrs returns a new asyncHandler sharing the same queue.
+func (h *asyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &asyncHandler{handler: h.handler.WithAttrs(attrs), queue: h.queue}
+}
+
+// WithGroup returns a new asyncHandler sharing the same queue.
+func (h *asyncHandler) WithGroup(name string) slog.Handler {
+	return &asyncHandler{handler: h.handler.WithGroup(name), queue: h.queue}
+}
+
+// Handle queues the record. The record is cloned and the context detached
+// from its cancellation since both outlive the call. Once the handler is
+// closed, the record is written before Handle returns.
+func (h *asyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := asyncEntry{
+		ctx:     context.WithoutCancel(ctx),
+		handler: h.handler,
+		record:  r.Clone(),
+	}
+
+	h.queue.push(e)
+
+	return nil
+}
+
+// flush waits until every queued record has been written or the context is
+// done.
+func (h *asyncHandler) flush(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+
+	for h.queue.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("flushing logs: %d records pending: %w", h.queue.pending.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// close flushes the queue and stops the background goroutine. The records
+// still queued when the context is done are written before close returns,
+// along with the flush error.
+func (h *asyncHandler) close(ctx context.Context) error {
+	err := h.flush(ctx)
+
+	h.queue.mu.Lock()
+	if !h.queue.closed {
+		h.queue.closed = true
+		close(h.queue.done)
+	}
+	h.queue.mu.Unlock()
+
+	<-h.queue.stopped
+
+	return err
+}
+
+// =============================================================================
+
+// push adds the entry to the queue applying the overflow policy when full.
+// Once the queue is closed, the entry is written right away instead.
+func (q *asyncQueue) push(e asyncEntry) {
+	q.pending.Add(1)
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.write(e)
+		return
+	}
+
+	switch q.overflow {
+	case OverflowDropNewest:
+		select {
+		case q.entries <- e:
+		default:
+			q.drop()
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.entries <- e:
+				return
+			default:
+			}
+
+			select {
+			case <-q.entries:
+				q.drop()
+			default:
+			}
+		}
+
+	default:
+		q.entries <- e
+	}
+}
+
+// drop accounts for a record that will never be written.
+func (q *asyncQueue) drop() {
+	q.pending.Add(-1)
+	droppedRecords.Add(1)
+}
+
+// run writes the queued records until the queue is closed, then writes
+// whatever is left in it.
+func (q *asyncQueue) run() {
+	defer close(q.stopped)
+
+	for {
+		select {
+		case e := <-q.entries:
+			q.write(e)
+
+		case <-q.done:
+			for {
+				select {
+				case e := <-q.entries:
+					q.write(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write passes the entry to its handler.
+func (q *asyncQueue) write(e asyncEntry) {
+	e.handler.Handle(e.ctx, e.record)
+	q.pending.Add(-1)
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey int
+
+const attrsKey ctxKey = 1
+
+// AddAttrs returns a copy of the context carrying the attributes, specified
+// as alternating keys and values or as slog.Attr values. Every record logged
+// with the returned context, or a context derived from it, includes them
+// along with any attributes added to the context earlier. The attributes are
+// paired up when they're added, so a key without a value is logged under
+// !BADKEY without shifting the attributes of other calls.
+func AddAttrs(ctx context.Context, attrs ...any) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	existing := attrsFromContext(ctx)
+
+	var r slog.Record
+	r.Add(attrs...)
+
+	all := make([]slog.Attr, 0, len(existing)+r.NumAttrs())
+	all = append(all, existing...)
+	r.Attrs(func(a slog.Attr) bool {
+		all = append(all, a)
+		return true
+	})
+
+	return context.WithValue(ctx, attrsKey, all)
+}
+
+// attrsFromContext returns the attributes added to the context.
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey).([]slog.Attr)
+	return attrs
+}
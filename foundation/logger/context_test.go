@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestAddAttrs(t *testing.T) {
+	tt := []struct {
+		name  string
+		calls [][]any
+		exp   []slog.Attr
+	}{
+		{
+			name:  "pairs",
+			calls: [][]any{{"a", 1}, {"b", 2}},
+			exp:   []slog.Attr{slog.Int("a", 1), slog.Int("b", 2)},
+		},
+		{
+			name:  "attrs",
+			calls: [][]any{{slog.String("a", "x")}, {"b", 2}},
+			exp:   []slog.Attr{slog.String("a", "x"), slog.Int("b", 2)},
+		},
+		{
+			name:  "dangling key",
+			calls: [][]any{{"a", 1, "dangling"}, {"b", 2}},
+			exp:   []slog.Attr{slog.Int("a", 1), slog.String("!BADKEY", "dangling"), slog.Int("b", 2)},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, attrs := range tst.calls {
+				ctx = AddAttrs(ctx, attrs...)
+			}
+
+			got := attrsFromContext(ctx)
+			if len(got) != len(tst.exp) {
+				t.Fatalf("got %v, exp %v", got, tst.exp)
+			}
+
+			for i := range got {
+				if !got[i].Equal(tst.exp[i]) {
+					t.Errorf("attr %d: got %v, exp %v", i, got[i], tst.exp[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// fanoutHandler writes every record to a set of handlers, each of which
+// decides for itself if the record is at a level it handles.
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func newFanoutHandler(handlers ...slog.Handler) *fanoutHandler {
+	return &fanoutHandler{
+		handlers: handlers,
+	}
+}
+
+// Enabled reports whether any of the handlers handles records at the given
+// level.
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hdl := range h.handlers {
+		if hdl.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithAttrs returns a new fanoutHandler whose handlers all have the
+// attributes added.
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hdl := range h.handlers {
+		handlers[i] = hdl.WithAttrs(attrs)
+	}
+
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup returns a new fanoutHandler whose handlers all have the group
+// opened.
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hdl := range h.handlers {
+		handlers[i] = hdl.WithGroup(name)
+	}
+
+	return &fanoutHandler{handlers: handlers}
+}
+
+// Handle writes the record to every handler that is enabled for its level.
+// A failing handler does not prevent the others from receiving the record.
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hdl := range h.handlers {
+		if !hdl.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		if err := hdl.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -11,12 +11,14 @@ import (
 type logHandler struct {
 	handler slog.Handler
 	events  Events
+	set     attrSet
 }
 
-func newLogHandler(handler slog.Handler, events Events) *logHandler {
+func newLogHandler(handler slog.Handler, events Events, rd *redactor) *logHandler {
 	return &logHandler{
 		handler: handler,
 		events:  events,
+		set:     attrSet{redactor: rd},
 	}
 }
 
@@ -29,14 +31,14 @@ func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
 // WithAttrs returns a new JSONHandler whose attributes consists
 // of h's attributes followed by attrs.
 func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &logHandler{handler: h.handler.WithAttrs(attrs), events: h.events}
+	return &logHandler{handler: h.handler.WithAttrs(attrs), events: h.events, set: h.set.withAttrs(attrs)}
 }
 
 // WithGroup returns a new Handler with the given group appended to the receiver's
 // existing groups. The keys of all subsequent attributes, whether added by With
 // or in a Record, should be qualified by the sequence of group names.
 func (h *logHandler) WithGroup(name string) slog.Handler {
-	return &logHandler{handler: h.handler.WithGroup(name), events: h.events}
+	return &logHandler{handler: h.handler.WithGroup(name), events: h.events, set: h.set.withGroup(name)}
 }
 
 // Handle looks to see if an event function needs to be executed for a given
@@ -45,22 +47,22 @@ func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
 	switch r.Level {
 	case slog.LevelDebug:
 		if h.events.Debug != nil {
-			h.events.Debug(ctx, toRecord(r))
+			h.events.Debug(ctx, h.set.toRecord(r))
 		}
 
 	case slog.LevelError:
 		if h.events.Error != nil {
-			h.events.Error(ctx, toRecord(r))
+			h.events.Error(ctx, h.set.toRecord(r))
 		}
 
 	case slog.LevelWarn:
 		if h.events.Warn != nil {
-			h.events.Warn(ctx, toRecord(r))
+			h.events.Warn(ctx, h.set.toRecord(r))
 		}
 
 	case slog.LevelInfo:
 		if h.events.Info != nil {
-			h.events.Info(ctx, toRecord(r))
+			h.events.Info(ctx, h.set.toRecord(r))
 		}
 	}
 
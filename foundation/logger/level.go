@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// levelControl holds the minimum level the handlers compare against so it can
+// be changed while the service is running.
+type levelControl struct {
+	level slog.LevelVar
+	base  Level // level configured at construction
+
+	mu       sync.Mutex
+	revert   *time.Timer
+	previous Level
+}
+
+func newLevelControl(minLevel Level) *levelControl {
+	lc := levelControl{
+		base: minLevel,
+	}
+	lc.level.Set(slog.Level(minLevel))
+
+	return &lc
+}
+
+// get returns the current minimum level.
+func (lc *levelControl) get() Level {
+	return Level(lc.level.Level())
+}
+
+// set changes the minimum level and cancels any pending reversion.
+func (lc *levelControl) set(level Level) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.stopRevert()
+	lc.level.Set(slog.Level(level))
+}
+
+// setFor changes the minimum level and restores the level that was active
+// before the first temporary change once the ttl expires. Calling it again
+// before the ttl expires extends the window without losing the level to
+// revert to.
+func (lc *levelControl) setFor(level Level, ttl time.Duration) Level {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	previous := lc.get()
+	if lc.stopRevert() {
+		previous = lc.previous
+	}
+
+	lc.previous = previous
+	lc.level.Set(slog.Level(level))
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+
+		// A newer call may have replaced this timer after it fired but
+		// before the lock was acquired.
+		if lc.revert != timer {
+			return
+		}
+
+		lc.revert = nil
+		lc.level.Set(slog.Level(lc.previous))
+	})
+	lc.revert = timer
+
+	return previous
+}
+
+// stopRevert cancels a pending reversion and reports whether there was one.
+// The caller must hold the mutex.
+func (lc *levelControl) stopRevert() bool {
+	if lc.revert == nil {
+		return false
+	}
+
+	lc.revert.Stop()
+	lc.revert = nil
+
+	return true
+}
@@ -20,16 +20,30 @@ type TracerIDFn func(ctx context.Context) string
 type Logger struct {
 	handler   slog.Handler
 	traceIDFn TracerIDFn // private
+	level     *levelControl
+	sinks     []sinkLeveler
+	async     *asyncHandler
 }
 
 // New creates a new Logger instance.
 func New(w io.Writer, minLevel Level, serviceName string, traceIDFn TracerIDFn) *Logger {
-	return new(w, minLevel, serviceName, traceIDFn, Events{})
+	return new(Config{
+		ServiceName: serviceName,
+		MinLevel:    minLevel,
+		TraceIDFn:   traceIDFn,
+		Sinks:       []Sink{{Writer: w, Format: FormatJSON, MinLevel: minLevel}},
+	})
 }
 
 // NewWithEvents creates a new Logger instance with custom event functions.
 func NewWithEvents(w io.Writer, minLevel Level, serviceName string, traceIDFn TracerIDFn, events Events) *Logger {
-	return new(w, minLevel, serviceName, traceIDFn, events)
+	return new(Config{
+		ServiceName: serviceName,
+		MinLevel:    minLevel,
+		TraceIDFn:   traceIDFn,
+		Events:      events,
+		Sinks:       []Sink{{Writer: w, Format: FormatJSON, MinLevel: minLevel}},
+	})
 }
 
 // NewWithHandler creates a new Logger instance with a custom handler.
@@ -39,6 +53,77 @@ func NewWithHandler(handler slog.Handler) *Logger {
 	}
 }
 
+// Level returns the logger's level. While it's at its configured level,
+// every sink writes at its own MinLevel instead, see SinkLevels for the
+// levels actually applied.
+func (l *Logger) Level() Level {
+	if l.level == nil {
+		return LevelDebug
+	}
+
+	return l.level.get()
+}
+
+// SinkLevels returns the minimum level each sink is currently writing at, in
+// the order the sinks were configured.
+func (l *Logger) SinkLevels() []Level {
+	levels := make([]Level, len(l.sinks))
+	for i, sl := range l.sinks {
+		levels[i] = Level(sl.Level())
+	}
+
+	return levels
+}
+
+// SetLevel changes the minimum level being logged. Any pending reversion
+// scheduled by SetLevelFor is canceled. Loggers constructed with
+// NewWithHandler manage their own level and ignore this call.
+func (l *Logger) SetLevel(level Level) {
+	if l.level == nil {
+		return
+	}
+
+	l.level.set(level)
+}
+
+// SetLevelFor changes the minimum level being logged for the specified
+// duration, after which the level that was active before is restored. It
+// returns the level that will be restored.
+func (l *Logger) SetLevelFor(level Level, ttl time.Duration) Level {
+	if l.level == nil {
+		return l.Level()
+	}
+
+	return l.level.setFor(level, ttl)
+}
+
+// Flush blocks until every record queued for asynchronous writing has been
+// written or the context is done. It is a no-op for synchronous loggers.
+func (l *Logger) Flush(ctx context.Context) error {
+	if l.async == nil {
+		return nil
+	}
+
+	return l.async.flush(ctx)
+}
+
+// Close flushes the queued records like Flush and stops the goroutine
+// writing them. Records logged after Close are written synchronously. It is
+// a no-op for synchronous loggers.
+func (l *Logger) Close(ctx context.Context) error {
+	if l.async == nil {
+		return nil
+	}
+
+	return l.async.close(ctx)
+}
+
+// Enabled reports whether a record at the specified level would be written
+// by any sink. It allows skipping expensive work only needed for the record.
+func (l *Logger) Enabled(ctx context.Context, level Level) bool {
+	return l.handler.Enabled(ctx, slog.Level(level))
+}
+
 // NewStdLogger returns a standard library Logger that wraps the slog Logger.
 func NewStdLogger(logger *Logger, level Level) *log.Logger {
 	return slog.NewLogLogger(logger.handler, slog.Level(level))
@@ -96,49 +181,108 @@ func (l *Logger) write(ctx context.Context, level Level, callDepth int, message
 	runtime.Callers(callDepth, pcs[:])
 	// Create a new log record.
 	r := slog.NewRecord(time.Now(), slogLevel, message, pcs[0])
+	// Add the attributes to the log record. They are added on their own so
+	// a dangling key can't pair with the attributes added after them.
+	r.Add(attrs...)
+	// Add the attributes scoped to the context by AddAttrs.
+	r.AddAttrs(attrsFromContext(ctx)...)
 	// if the traceIDFn is not nil, then we add the trace ID to the log record.
 	if l.traceIDFn != nil {
-		attrs = append(attrs, "trace_id", l.traceIDFn(ctx))
+		r.AddAttrs(slog.String("trace_id", l.traceIDFn(ctx)))
 	}
-	// Add the attributes to the log record.
-	r.Add(attrs...)
 
 	// Call the handler to handle the log record.
 	l.handler.Handle(ctx, r)
 }
 
-func new(w io.Writer, minLevel Level, serviceName string, traceIDFn TracerIDFn, events Events) *Logger {
-	// convert the file name to just the name.ext format.
-	fn := func(groups []string, a slog.Attr) slog.Attr {
-		if a.Key == slog.SourceKey {
-			if source, ok := a.Value.Any().(*slog.Source); ok {
-				v := fmt.Sprintf("%s:%d", filepath.Base(source.File), source.Line)
-				return slog.Attr{Key: "file", Value: slog.StringValue(v)}
-			}
+func new(cfg Config) *Logger {
+	// The level is held in a variable so it can be changed at runtime.
+	level := newLevelControl(cfg.MinLevel)
+
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []Sink{{Format: FormatJSON, MinLevel: cfg.MinLevel}}
+	}
 
+	// Build the ReplaceAttr pipeline, redacting sensitive values before the
+	// source is reformatted.
+	replace := replaceSource
+	var rd *redactor
+	if cfg.Redact != nil {
+		rd = newRedactor(*cfg.Redact)
+		replace = func(groups []string, a slog.Attr) slog.Attr {
+			return replaceSource(groups, rd.replaceAttr(groups, a))
 		}
-		return a
-	}
-	handler := slog.Handler(slog.NewJSONHandler(w, &slog.HandlerOptions{
-		Level:       slog.Level(minLevel),
-		AddSource:   true,
-		ReplaceAttr: fn,
-	}))
-	// If events are to be processed, wrap the JSON handler around the custom
+	}
+
+	handlers := make([]slog.Handler, 0, len(sinks)+1)
+	levelers := make([]sinkLeveler, len(sinks))
+	for i, sink := range sinks {
+		levelers[i] = sinkLeveler{lc: level, sink: slog.Level(sink.MinLevel)}
+		handlers = append(handlers, newSinkHandler(sink, levelers[i], replace))
+	}
+
+	// The ring buffer keeps the records at the logger's level in memory.
+	if cfg.Ring != nil {
+		handlers = append(handlers, &ringHandler{
+			rb:    cfg.Ring,
+			level: sinkLeveler{lc: level, sink: slog.Level(cfg.MinLevel)},
+			set:   attrSet{redactor: rd},
+		})
+	}
+
+	var handler slog.Handler
+	switch len(handlers) {
+	case 1:
+		handler = handlers[0]
+	default:
+		handler = newFanoutHandler(handlers...)
+	}
+
+	// If events are to be processed, wrap the handler around the custom
 	// log handler.
+	events := cfg.Events
 	if events.Debug != nil || events.Info != nil || events.Warn != nil || events.Error != nil {
-		handler = newLogHandler(handler, events)
+		handler = newLogHandler(handler, events, rd)
 	}
 	// Attributes to add to every log.
 	attrs := []slog.Attr{
-		{Key: "service", Value: slog.StringValue(serviceName)},
+		{Key: "service", Value: slog.StringValue(cfg.ServiceName)},
 	}
 
 	// Add those attributes and capture the final handler.
 	handler = handler.WithAttrs(attrs)
 
+	// Sampling wraps everything so dropped records cost as little as
+	// possible and the summary records carry the service attributes.
+	if cfg.Sample != nil {
+		handler = newSampleHandler(handler, *cfg.Sample)
+	}
+
+	// The async handler is last so the logging goroutine only pays for
+	// queueing the record.
+	var async *asyncHandler
+	if cfg.Async != nil {
+		async = newAsyncHandler(handler, *cfg.Async)
+		handler = async
+	}
+
 	return &Logger{
 		handler:   handler,
-		traceIDFn: traceIDFn,
+		traceIDFn: cfg.TraceIDFn,
+		level:     level,
+		sinks:     levelers,
+		async:     async,
+	}
+}
+
+// replaceSource converts the source attribute to just the name.ext:line format.
+func replaceSource(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.SourceKey {
+		if source, ok := a.Value.Any().(*slog.Source); ok {
+			v := fmt.Sprintf("%s:%d", filepath.Base(source.File), source.Line)
+			return slog.Attr{Key: "file", Value: slog.StringValue(v)}
+		}
 	}
+	return a
 }
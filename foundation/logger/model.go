@@ -2,6 +2,10 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -18,7 +22,25 @@ const (
 	LevelError = Level(slog.LevelError)
 )
 
-// Record represents a log record.
+// String returns the name of the level.
+func (l Level) String() string {
+	return slog.Level(l).String()
+}
+
+// ParseLevel parses a level name such as "DEBUG" or "warn" into a Level.
+func ParseLevel(name string) (Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return 0, err
+	}
+
+	return Level(l), nil
+}
+
+// Record represents a log record. Attributes holds every attribute attached
+// to the record, including those added to the handler, with keys qualified by
+// their group names (group.key). The trace ID is found under "trace_id" and
+// the source location under "file".
 type Record struct {
 	Level      Level
 	Time       time.Time
@@ -26,20 +48,113 @@ type Record struct {
 	Attributes map[string]any
 }
 
+// attrSet tracks the attributes and groups added to a handler so a
+// slog.Record can be converted into a Record with its full context.
+type attrSet struct {
+	attrs    []slog.Attr // already qualified by the groups open when added
+	groups   []string
+	redactor *redactor // optional
+}
+
+// withAttrs returns a copy of the set with the attributes appended.
+func (s attrSet) withAttrs(attrs []slog.Attr) attrSet {
+	if len(attrs) == 0 {
+		return s
+	}
+
+	m := make(map[string]any, len(attrs))
+	prefix := strings.Join(s.groups, ".")
+	for _, attr := range attrs {
+		s.addAttr(m, prefix, attr)
+	}
+
+	all := make([]slog.Attr, len(s.attrs), len(s.attrs)+len(m))
+	copy(all, s.attrs)
+	for k, v := range m {
+		all = append(all, slog.Any(k, v))
+	}
+
+	return attrSet{attrs: all, groups: s.groups, redactor: s.redactor}
+}
+
+// withGroup returns a copy of the set with the group opened.
+func (s attrSet) withGroup(name string) attrSet {
+	if name == "" {
+		return s
+	}
+
+	groups := make([]string, len(s.groups), len(s.groups)+1)
+	copy(groups, s.groups)
+
+	return attrSet{attrs: s.attrs, groups: append(groups, name), redactor: s.redactor}
+}
+
 // toRecord converts a slog.Record to a Record of our choice.
-func toRecord(r slog.Record) Record {
-	atts := make(map[string]any, r.NumAttrs())
+func (s attrSet) toRecord(r slog.Record) Record {
+	atts := make(map[string]any, len(s.attrs)+r.NumAttrs()+1)
+	for _, attr := range s.attrs {
+		atts[attr.Key] = attr.Value.Any()
+	}
+
+	prefix := strings.Join(s.groups, ".")
 	fn := func(attr slog.Attr) bool {
-		atts[attr.Key] = attr.Value
+		s.addAttr(atts, prefix, attr)
 		return true
 	}
 	r.Attrs(fn)
+
+	if r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		frame, _ := frames.Next()
+		atts["file"] = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+	}
+
+	msg := r.Message
+	if s.redactor != nil {
+		msg = s.redactor.scrub(msg)
+	}
+
 	return Record{
 		Level:      Level(r.Level),
 		Time:       r.Time,
-		Message:    r.Message,
-		Attributes: make(map[string]any),
+		Message:    msg,
+		Attributes: atts,
+	}
+}
+
+// addAttr resolves the attribute and adds it to the map, flattening groups
+// into dot separated keys the same way they nest in the JSON output.
+func (s attrSet) addAttr(m map[string]any, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if s.redactor != nil {
+		attr.Value = s.redactor.value(attr.Key, attr.Value)
 	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		key := prefix
+		if attr.Key != "" {
+			key = qualify(prefix, attr.Key)
+		}
+
+		for _, ga := range attr.Value.Group() {
+			s.addAttr(m, key, ga)
+		}
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	m[qualify(prefix, attr.Key)] = attr.Value.Any()
+}
+
+func qualify(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
 }
 
 // EventFn is a function that logs an event, that will be attached to the log level.
@@ -0,0 +1,368 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactMode represents how a sensitive value is hidden.
+type RedactMode string
+
+// Set of redaction modes.
+const (
+	// RedactMask replaces the value with a fixed placeholder.
+	RedactMask RedactMode = "mask"
+
+	// RedactHash replaces the value with a short hash of it so the same value
+	// can still be correlated across records without being revealed.
+	RedactHash RedactMode = "hash"
+)
+
+// redactedValue is the placeholder used by RedactMask.
+const redactedValue = "[REDACTED]"
+
+// ParseRedactMode parses a mode name such as "mask" or "hash" into a
+// RedactMode.
+func ParseRedactMode(name string) (RedactMode, error) {
+	switch m := RedactMode(strings.ToLower(name)); m {
+	case RedactMask, RedactHash:
+		return m, nil
+	}
+
+	return "", fmt.Errorf("unknown redact mode %q", name)
+}
+
+// DefaultRedactKeys is the set of key patterns whose values are redacted when
+// no keys are configured.
+var DefaultRedactKeys = []string{"password", "token", "authorization", "email", "secret", "cookie"}
+
+// DefaultRedactDetectors is the set of value detectors used when no detectors
+// are configured. They find JWTs and bearer credentials anywhere in a string.
+var DefaultRedactDetectors = []*regexp.Regexp{
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`),
+}
+
+// RedactConfig represents the settings for hiding sensitive values.
+//
+// An attribute whose key contains any of the Keys, ignoring case, has its
+// value redacted. Query parameters matching the Keys are redacted inside URL
+// values, and any part of a string or error matching a detector is redacted
+// wherever it appears. Fields of logged structs tagged with `log:"redact"`
+// are always redacted.
+type RedactConfig struct {
+	Keys      []string
+	Detectors []*regexp.Regexp
+	Mode      RedactMode
+}
+
+// =============================================================================
+
+// redactor applies a RedactConfig to attributes.
+type redactor struct {
+	keys      []string
+	detectors []*regexp.Regexp
+	mode      RedactMode
+}
+
+func newRedactor(cfg RedactConfig) *redactor {
+	keys := cfg.Keys
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+
+	lower := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			lower = append(lower, key)
+		}
+	}
+
+	detectors := cfg.Detectors
+	if detectors == nil {
+		detectors = DefaultRedactDetectors
+	}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = RedactMask
+	}
+
+	return &redactor{
+		keys:      lower,
+		detectors: detectors,
+		mode:      mode,
+	}
+}
+
+// replaceAttr is used in the ReplaceAttr pipeline of the slog handlers.
+func (rd *redactor) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	// The built-in keys are never matched against the key patterns, but the
+	// message can still carry a credential.
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.TimeKey, slog.LevelKey, slog.SourceKey:
+			return a
+		case slog.MessageKey:
+			return slog.String(a.Key, rd.scrub(a.Value.String()))
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: rd.value(a.Key, a.Value)}
+}
+
+// value returns the redacted form of a value logged under the key.
+func (rd *redactor) value(key string, v slog.Value) slog.Value {
+	v = v.Resolve()
+
+	if rd.matchKey(key) && v.Kind() != slog.KindGroup {
+		return slog.StringValue(rd.redact(v.String()))
+	}
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(rd.scrubURL(rd.scrub(v.String())))
+
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			if s := x.Error(); rd.scrub(s) != s {
+				return slog.StringValue(rd.scrub(s))
+			}
+			return v
+		}
+
+		if converted, ok := rd.taggedValue(reflect.ValueOf(v.Any())); ok {
+			return slog.AnyValue(converted)
+		}
+	}
+
+	return v
+}
+
+// matchKey reports whether the key matches any of the key patterns.
+func (rd *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range rd.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redact hides the value according to the configured mode.
+func (rd *redactor) redact(s string) string {
+	if rd.mode == RedactHash {
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+
+	return redactedValue
+}
+
+// scrub redacts any part of the string matching a detector.
+func (rd *redactor) scrub(s string) string {
+	for _, re := range rd.detectors {
+		s = re.ReplaceAllStringFunc(s, rd.redact)
+	}
+
+	return s
+}
+
+// scrubURL redacts the query parameters of a URL, or of a path with a query
+// string, whose names match the key patterns.
+func (rd *redactor) scrubURL(s string) string {
+	i := strings.IndexByte(s, '?')
+	if i < 0 || strings.ContainsAny(s, " \t\n") {
+		return s
+	}
+
+	query, err := url.ParseQuery(s[i+1:])
+	if err != nil {
+		return s
+	}
+
+	var changed bool
+	for name, values := range query {
+		if !rd.matchKey(name) {
+			continue
+		}
+
+		for j := range values {
+			values[j] = rd.redact(values[j])
+		}
+		changed = true
+	}
+
+	if !changed {
+		return s
+	}
+
+	return s[:i+1] + query.Encode()
+}
+
+// =============================================================================
+
+// redactTag is the struct tag used to mark fields that must never be logged.
+const redactTag = "redact"
+
+// structFields caches, per type, whether it holds a struct with a field
+// tagged with `log:"redact"` so values without one are logged as they are.
+var structFields sync.Map
+
+// taggedValue converts a struct, or a pointer, slice, array or map holding
+// structs, whose type has fields tagged with `log:"redact"` into maps and
+// slices with those fields redacted. It reports false when the value
+// doesn't need to be converted.
+func (rd *redactor) taggedValue(v reflect.Value) (any, bool) {
+	if !v.IsValid() || !hasRedactTag(v.Type()) {
+		return nil, false
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return rd.structValue(v), true
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, false
+		}
+
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = rd.fieldValue(v.Index(i))
+		}
+		return s, true
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = rd.fieldValue(iter.Value())
+		}
+		return m, true
+	}
+
+	return nil, false
+}
+
+// structValue converts the struct into a map with the tagged fields
+// redacted.
+func (rd *redactor) structValue(v reflect.Value) map[string]any {
+	t := v.Type()
+	m := make(map[string]any, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+
+		name := fld.Name
+		if tag, _, _ := strings.Cut(fld.Tag.Get("json"), ","); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+
+		if hasTagOption(fld.Tag.Get("log"), redactTag) {
+			m[name] = rd.redact(fmt.Sprint(v.Field(i).Interface()))
+			continue
+		}
+
+		m[name] = rd.fieldValue(v.Field(i))
+	}
+
+	return m
+}
+
+// fieldValue returns the value to log for a field or element, converted when
+// it holds tagged structs.
+func (rd *redactor) fieldValue(v reflect.Value) any {
+	if converted, ok := rd.taggedValue(v); ok {
+		return converted
+	}
+
+	return v.Interface()
+}
+
+// hasRedactTag reports whether the type holds a struct with a field tagged
+// with `log:"redact"`, directly or through pointers, slices, arrays, maps
+// or nested structs. Only the final result is cached so concurrent callers
+// never see a partial one.
+func hasRedactTag(t reflect.Type) bool {
+	if found, ok := structFields.Load(t); ok {
+		return found.(bool)
+	}
+
+	found := containsRedactTag(t, make(map[reflect.Type]bool))
+	structFields.Store(t, found)
+
+	return found
+}
+
+// containsRedactTag walks the type looking for a tagged field. The visited
+// types are skipped so recursive types terminate.
+func containsRedactTag(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	if found, ok := structFields.Load(t); ok {
+		return found.(bool)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return containsRedactTag(t.Elem(), visited)
+
+	case reflect.Map:
+		return containsRedactTag(t.Key(), visited) || containsRedactTag(t.Elem(), visited)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			fld := t.Field(i)
+			if !fld.IsExported() {
+				continue
+			}
+
+			if hasTagOption(fld.Tag.Get("log"), redactTag) || containsRedactTag(fld.Type, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func hasTagOption(tag string, option string) bool {
+	for _, opt := range strings.Split(tag, ",") {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+
+	return false
+}
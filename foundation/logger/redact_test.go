@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type redactUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password" log:"redact"`
+}
+
+type redactPlain struct {
+	Name string
+}
+
+type redactNode struct {
+	Name string
+	Next *redactNode
+}
+
+type redactTeam struct {
+	Lead    *redactUser
+	Members []redactUser
+	ByName  map[string]redactUser
+}
+
+func TestHasRedactTag(t *testing.T) {
+	tt := []struct {
+		name string
+		typ  reflect.Type
+		exp  bool
+	}{
+		{"tagged", reflect.TypeOf(redactUser{}), true},
+		{"pointer", reflect.TypeOf(&redactUser{}), true},
+		{"slice", reflect.TypeOf([]redactUser{}), true},
+		{"array", reflect.TypeOf([2]*redactUser{}), true},
+		{"map", reflect.TypeOf(map[string]redactUser{}), true},
+		{"nested", reflect.TypeOf(redactTeam{}), true},
+		{"plain", reflect.TypeOf(redactPlain{}), false},
+		{"recursive", reflect.TypeOf(redactNode{}), false},
+		{"string", reflect.TypeOf(""), false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := hasRedactTag(tst.typ); got != tst.exp {
+				t.Errorf("got %v, exp %v", got, tst.exp)
+			}
+		})
+	}
+}
+
+func TestHasRedactTagConcurrent(t *testing.T) {
+	type fresh struct {
+		Secret string `log:"redact"`
+	}
+
+	typ := reflect.TypeOf(fresh{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !hasRedactTag(typ) {
+				t.Error("got false for a tagged type")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRedactValue(t *testing.T) {
+	rd := newRedactor(RedactConfig{Keys: []string{"nothing-matches"}})
+
+	tt := []struct {
+		name string
+		val  any
+		exp  any
+	}{
+		{
+			name: "struct",
+			val:  redactUser{Name: "bill", Password: "gophers"},
+			exp:  map[string]any{"name": "bill", "password": redactedValue},
+		},
+		{
+			name: "pointer",
+			val:  &redactUser{Name: "bill", Password: "gophers"},
+			exp:  map[string]any{"name": "bill", "password": redactedValue},
+		},
+		{
+			name: "slice",
+			val:  []redactUser{{Name: "bill", Password: "gophers"}},
+			exp:  []any{map[string]any{"name": "bill", "password": redactedValue}},
+		},
+		{
+			name: "map",
+			val:  map[string]*redactUser{"a": {Name: "bill", Password: "gophers"}},
+			exp:  map[string]any{"a": map[string]any{"name": "bill", "password": redactedValue}},
+		},
+		{
+			name: "nested",
+			val: redactTeam{
+				Lead:    &redactUser{Name: "bill", Password: "gophers"},
+				Members: []redactUser{{Name: "ed", Password: "pass"}},
+			},
+			exp: map[string]any{
+				"Lead":    map[string]any{"name": "bill", "password": redactedValue},
+				"Members": []any{map[string]any{"name": "ed", "password": redactedValue}},
+				"ByName":  map[string]redactUser(nil),
+			},
+		},
+		{
+			name: "plain",
+			val:  redactPlain{Name: "bill"},
+			exp:  redactPlain{Name: "bill"},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			got := rd.value("user", slog.AnyValue(tst.val)).Any()
+			if !reflect.DeepEqual(got, tst.exp) {
+				t.Errorf("got %#v, exp %#v", got, tst.exp)
+			}
+		})
+	}
+}
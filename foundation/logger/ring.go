@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RingBuffer keeps the most recent records logged at each level in memory so
+// they can be inspected while the service is running.
+type RingBuffer struct {
+	size int
+
+	mu    sync.Mutex
+	rings map[Level]*ring
+}
+
+// NewRingBuffer constructs a buffer keeping the last size records per level.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = 1000
+	}
+
+	return &RingBuffer{
+		size:  size,
+		rings: make(map[Level]*ring),
+	}
+}
+
+// RingFilter represents the set of conditions a record must match to be
+// returned from the buffer. Zero values match everything except MinLevel,
+// whose zero value is LevelInfo; set it to LevelDebug to include debug
+// records.
+type RingFilter struct {
+	MinLevel Level
+	TraceID  string
+	Since    time.Time
+	Until    time.Time
+}
+
+// Records returns the buffered records matching the filter, oldest first.
+func (rb *RingBuffer) Records(filter RingFilter) []Record {
+	rb.mu.Lock()
+	var records []Record
+	for level, rg := range rb.rings {
+		if level < filter.MinLevel {
+			continue
+		}
+		records = append(records, rg.records()...)
+	}
+	rb.mu.Unlock()
+
+	matched := records[:0]
+	for _, r := range records {
+		if filter.TraceID != "" {
+			if traceID, _ := r.Attributes["trace_id"].(string); traceID != filter.TraceID {
+				continue
+			}
+		}
+
+		if !filter.Since.IsZero() && r.Time.Before(filter.Since) {
+			continue
+		}
+
+		if !filter.Until.IsZero() && r.Time.After(filter.Until) {
+			continue
+		}
+
+		matched = append(matched, r)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Time.Before(matched[j].Time)
+	})
+
+	return matched
+}
+
+// add stores the record, overwriting the oldest record of the same level
+// once the ring is full.
+func (rb *RingBuffer) add(r Record) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rg, exists := rb.rings[r.Level]
+	if !exists {
+		rg = &ring{buf: make([]Record, 0, rb.size)}
+		rb.rings[r.Level] = rg
+	}
+
+	rg.add(r)
+}
+
+// =============================================================================
+
+// ring is a fixed size circular buffer of records.
+type ring struct {
+	buf  []Record
+	next int
+}
+
+// add stores the record, overwriting the oldest one when full.
+func (rg *ring) add(r Record) {
+	if len(rg.buf) < cap(rg.buf) {
+		rg.buf = append(rg.buf, r)
+		return
+	}
+
+	rg.buf[rg.next] = r
+	rg.next = (rg.next + 1) % len(rg.buf)
+}
+
+// records returns a copy of the buffered records, oldest first.
+func (rg *ring) records() []Record {
+	out := make([]Record, 0, len(rg.buf))
+	out = append(out, rg.buf[rg.next:]...)
+	out = append(out, rg.buf[:rg.next]...)
+
+	return out
+}
+
+// =============================================================================
+
+// ringHandler resolves records and stores them in a RingBuffer.
+type ringHandler struct {
+	rb    *RingBuffer
+	level slog.Leveler
+	set   attrSet
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *ringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// WithAttrs returns a new ringHandler with the attributes added.
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ringHandler{rb: h.rb, level: h.level, set: h.set.withAttrs(attrs)}
+}
+
+// WithGroup returns a new ringHandler with the group opened.
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	return &ringHandler{rb: h.rb, level: h.level, set: h.set.withGroup(name)}
+}
+
+// Handle stores the resolved record in the buffer.
+func (h *ringHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.rb.add(h.set.toRecord(r))
+	return nil
+}
@@ -0,0 +1,401 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default maximum size of a log file before it is rotated.
+const defaultMaxSize = 100 << 20
+
+// rotateTimeFormat is used to name rotated segments so they sort in the order
+// they were created.
+const rotateTimeFormat = "20060102T150405.000000000"
+
+// FileConfig represents the settings for a rotating log file.
+type FileConfig struct {
+	// Path is the file records are written to. Rotated segments are placed
+	// next to it as name-<timestamp>.ext.
+	Path string
+
+	// MaxSize is the size in bytes at which the file is rotated. A value of
+	// zero uses a default of 100MB.
+	MaxSize int64
+
+	// RotateEvery rotates the file once it has been open this long, even if
+	// it hasn't reached MaxSize. Zero disables time based rotation.
+	RotateEvery time.Duration
+
+	// MaxBackups is the number of rotated segments to keep. Zero keeps them
+	// all.
+	MaxBackups int
+
+	// MaxAge is how long rotated segments are kept. Zero keeps them
+	// regardless of age.
+	MaxAge time.Duration
+
+	// Compress gzips rotated segments.
+	Compress bool
+
+	// OnError is called with the errors of compressing and removing rotated
+	// segments, which happen in the background. Nil writes them to stderr.
+	OnError func(err error)
+}
+
+// FileWriter is an io.WriteCloser that writes to a file and rotates it based
+// on size and age. It is safe for concurrent use.
+type FileWriter struct {
+	cfg FileConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// Rotated segments are handed to a single worker so compression and
+	// cleanup never run at the same time. They're queued in pending and
+	// the worker is woken through notify, so a slow worker never blocks
+	// the writers.
+	pending []string
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+// NewFileWriter opens, or creates, the configured file for appending.
+func NewFileWriter(cfg FileConfig) (*FileWriter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("log file path is required")
+	}
+
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMaxSize
+	}
+
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) {
+			fmt.Fprintln(os.Stderr, "log file:", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	fw := FileWriter{
+		cfg:    cfg,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	if err := fw.open(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer close(fw.done)
+		fw.process()
+	}()
+
+	return &fw, nil
+}
+
+// Write implements the io.Writer interface. The file is rotated before the
+// write if the write would take it past its maximum size or it has been open
+// longer than the rotation period. If the file couldn't be reopened after a
+// rotation, opening it is tried again.
+func (fw *FileWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if err := fw.reopen(); err != nil {
+		return 0, err
+	}
+
+	if fw.shouldRotate(int64(len(p))) {
+		if err := fw.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := fw.file.Write(p)
+	fw.size += int64(n)
+
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside and opens a new one.
+func (fw *FileWriter) Rotate() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if err := fw.reopen(); err != nil {
+		return err
+	}
+
+	return fw.rotate()
+}
+
+// Close closes the file and waits for the pending compression and cleanup
+// to complete.
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	var err error
+	if fw.file != nil {
+		err = fw.file.Close()
+		fw.file = nil
+	}
+	fw.closed = true
+	fw.mu.Unlock()
+
+	fw.wake()
+	<-fw.done
+
+	return err
+}
+
+// =============================================================================
+
+// reopen opens the file again when a rotation failed to. The caller must
+// hold the mutex.
+func (fw *FileWriter) reopen() error {
+	if fw.closed {
+		return os.ErrClosed
+	}
+
+	if fw.file != nil {
+		return nil
+	}
+
+	return fw.open()
+}
+
+// open opens the configured file for appending. The caller must hold the
+// mutex or be the constructor.
+func (fw *FileWriter) open() error {
+	f, err := os.OpenFile(fw.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	fw.file = f
+	fw.size = info.Size()
+	fw.opened = time.Now()
+
+	return nil
+}
+
+func (fw *FileWriter) shouldRotate(n int64) bool {
+	if fw.size > 0 && fw.size+n > fw.cfg.MaxSize {
+		return true
+	}
+
+	if fw.cfg.RotateEvery > 0 && fw.size > 0 && time.Since(fw.opened) >= fw.cfg.RotateEvery {
+		return true
+	}
+
+	return false
+}
+
+// rotate moves the current file aside and opens a new one. Compression and
+// cleanup of old segments happen in the background worker. The caller must
+// hold the mutex.
+func (fw *FileWriter) rotate() error {
+	err := fw.file.Close()
+	fw.file = nil
+	if err != nil {
+		return fmt.Errorf("closing log file: %w", err)
+	}
+
+	segment := fw.segmentName(time.Now())
+	if err := os.Rename(fw.cfg.Path, segment); err != nil {
+		if err := fw.open(); err != nil {
+			return err
+		}
+		return fmt.Errorf("renaming log file: %w", err)
+	}
+
+	fw.pending = append(fw.pending, segment)
+	fw.wake()
+
+	return fw.open()
+}
+
+// wake lets the worker know there is work without waiting for it.
+func (fw *FileWriter) wake() {
+	select {
+	case fw.notify <- struct{}{}:
+	default:
+	}
+}
+
+// process compresses every rotated segment and then removes the old ones,
+// one segment at a time, until the writer is closed.
+func (fw *FileWriter) process() {
+	for {
+		fw.mu.Lock()
+		segments := fw.pending
+		fw.pending = nil
+		closed := fw.closed
+		fw.mu.Unlock()
+
+		if len(segments) == 0 {
+			if closed {
+				return
+			}
+			<-fw.notify
+			continue
+		}
+
+		for _, segment := range segments {
+			fw.processSegment(segment)
+		}
+	}
+}
+
+// processSegment compresses the segment and removes the old ones.
+func (fw *FileWriter) processSegment(segment string) {
+	// A segment still queued may already have been removed by the cleanup
+	// of a newer one.
+	if fw.cfg.Compress {
+		if err := compress(segment); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fw.cfg.OnError(fmt.Errorf("compressing %s: %w", segment, err))
+		}
+	}
+
+	if err := fw.cleanup(); err != nil {
+		fw.cfg.OnError(fmt.Errorf("cleanup: %w", err))
+	}
+}
+
+// segmentName returns the name a rotated segment is moved to.
+func (fw *FileWriter) segmentName(t time.Time) string {
+	dir, base, ext := fw.parts()
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", base, t.UTC().Format(rotateTimeFormat), ext))
+}
+
+// parts splits the configured path into its directory, base name and
+// extension.
+func (fw *FileWriter) parts() (string, string, string) {
+	dir := filepath.Dir(fw.cfg.Path)
+	name := filepath.Base(fw.cfg.Path)
+	ext := filepath.Ext(name)
+
+	return dir, strings.TrimSuffix(name, ext), ext
+}
+
+// cleanup removes rotated segments beyond the configured number of backups
+// or older than the configured age.
+func (fw *FileWriter) cleanup() error {
+	if fw.cfg.MaxBackups <= 0 && fw.cfg.MaxAge <= 0 {
+		return nil
+	}
+
+	type segment struct {
+		path string
+		t    time.Time
+	}
+
+	dir, base, ext := fw.parts()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var segments []segment
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".gz")
+		if !strings.HasPrefix(name, base+"-") || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, base+"-"), ext)
+		t, err := time.Parse(rotateTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+
+		segments = append(segments, segment{path: filepath.Join(dir, entry.Name()), t: t})
+	}
+
+	// Newest first.
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].t.After(segments[j].t)
+	})
+
+	var errs []error
+	for i, seg := range segments {
+		expired := fw.cfg.MaxBackups > 0 && i >= fw.cfg.MaxBackups ||
+			fw.cfg.MaxAge > 0 && time.Since(seg.t) > fw.cfg.MaxAge
+
+		if expired {
+			if err := os.Remove(seg.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// compress gzips the segment and removes the original. The compressed file
+// is written under a temporary name and renamed once complete, so a partial
+// file is never taken for a segment. The original is kept if compression
+// fails.
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SampleConfig represents the settings for sampling records on hot paths.
+//
+// Within every Interval the First records logged with the same message and
+// level are kept, after which only every Thereafter record is kept. Records
+// at Warn and above are always kept. When records were dropped, a summary
+// record reporting how many is written as the interval ends.
+type SampleConfig struct {
+	Interval   time.Duration
+	First      int
+	Thereafter int
+}
+
+// sampleKey identifies the records counted together.
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+// sampler holds the counts shared by a sampling handler and every handler
+// derived from it with WithAttrs or WithGroup.
+type sampler struct {
+	cfg SampleConfig
+
+	// root is the handler as it was constructed, before any attributes or
+	// groups were added, so summary records don't inherit request values.
+	root slog.Handler
+
+	mu      sync.Mutex
+	start   time.Time
+	counts  map[sampleKey]int
+	dropped map[sampleKey]int
+	timer   *time.Timer
+}
+
+// sampleHandler drops records according to the sampler before they reach
+// the wrapped handler.
+type sampleHandler struct {
+	handler slog.Handler
+	sampler *sampler
+}
+
+func newSampleHandler(handler slog.Handler, cfg SampleConfig) *sampleHandler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.Thereafter <= 0 {
+		cfg.Thereafter = 1
+	}
+
+	s := sampler{
+		cfg:     cfg,
+		root:    handler,
+		start:   time.Now(),
+		counts:  make(map[sampleKey]int),
+		dropped: make(map[sampleKey]int),
+	}
+
+	return &sampleHandler{
+		handler: handler,
+		sampler: &s,
+	}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// WithAttrs returns a new sampleHandler sharing the same counts.
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{handler: h.handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup returns a new sampleHandler sharing the same counts.
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{handler: h.handler.WithGroup(name), sampler: h.sampler}
+}
+
+// Handle passes the record to the wrapped handler if the sampler keeps it.
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.sample(r) {
+		return nil
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+// =============================================================================
+
+// sample reports whether the record should be kept. The first record dropped
+// in an interval schedules the summary for when the interval ends.
+func (s *sampler) sample(r slog.Record) bool {
+	if r.Level >= slog.LevelWarn {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.start) >= s.cfg.Interval && s.timer == nil {
+		clear(s.counts)
+		s.start = now
+	}
+
+	key := sampleKey{level: r.Level, message: r.Message}
+	s.counts[key]++
+
+	n := s.counts[key]
+	if n <= s.cfg.First || (n-s.cfg.First)%s.cfg.Thereafter == 0 {
+		return true
+	}
+
+	s.dropped[key]++
+
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.start.Add(s.cfg.Interval).Sub(now), s.flush)
+	}
+
+	return false
+}
+
+// flush ends the interval and writes the summary of the records dropped
+// during it.
+func (s *sampler) flush() {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[sampleKey]int)
+	clear(s.counts)
+	s.start = time.Now()
+	s.timer = nil
+	s.mu.Unlock()
+
+	for key, n := range dropped {
+		s.writeSummary(key, n)
+	}
+}
+
+// writeSummary reports how many records with the key were dropped during the
+// last interval. The record carries no source since it isn't logged by any
+// caller.
+func (s *sampler) writeSummary(key sampleKey, dropped int) {
+	ctx := context.Background()
+
+	if !s.root.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "log sampling summary", 0)
+	r.Add("sampled_msg", key.message, "sampled_level", key.level.String(), "dropped", dropped, "interval", s.cfg.Interval.String())
+
+	s.root.Handle(ctx, r)
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes every message to its own .eml file in a folder instead of
+// sending it, so the emails can be read during local development and
+// tests.
+type File struct {
+	dir  string
+	from mail.Address
+}
+
+// NewFile constructs a File sender writing to the folder, which is created
+// if it doesn't exist.
+func NewFile(dir string, from mail.Address) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create folder: %w", err)
+	}
+
+	f := File{
+		dir:  dir,
+		from: from,
+	}
+
+	return &f, nil
+}
+
+// Send writes the message to a new file named after the time it was sent.
+// The file is renamed into place once written so readers never see a
+// partial message.
+func (f *File) Send(ctx context.Context, msg Message) error {
+	data, err := format(f.from, msg)
+	if err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("random: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	tmp, err := os.CreateTemp(f.dir, ".mail-*")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(f.dir, name)); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package mail provides support for sending emails through a pluggable
+// sender, with an SMTP implementation for production, a file
+// implementation for local development and tests, and a queue sending
+// through either in the background.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message represents a plain text email.
+type Message struct {
+	To      mail.Address
+	Subject string
+	Body    string
+}
+
+// Sender represents a value that can send emails.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders the message in the internet message format, ready to be
+// handed to a mail server or written to a file.
+func format(from mail.Address, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("subject contains a line break")
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("message id: %w", err)
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from.Address, "@"); i >= 0 {
+		domain = from.Address[i+1:]
+	}
+
+	var b bytes.Buffer
+	header := func(key string, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+
+	header("From", from.String())
+	header("To", msg.To.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Set of errors returned by Queue.Send when the message can't be queued.
+var (
+	ErrQueueFull   = errors.New("mail queue is full")
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+// QueueConfig represents the settings of a Queue. Zero values use the
+// defaults.
+type QueueConfig struct {
+	// Size is how many messages can wait to be sent, 100 by default.
+	Size int
+
+	// Timeout bounds the sending of a single message, 30s by default.
+	Timeout time.Duration
+
+	// OnError is called with the messages that failed to be sent. It's
+	// optional.
+	OnError func(msg Message, err error)
+}
+
+// queued is a message waiting to be sent along with the values of the
+// context it was queued with.
+type queued struct {
+	ctx context.Context
+	msg Message
+}
+
+// Queue sends the messages through another sender on a background
+// goroutine, so the caller doesn't wait for the mail server.
+type Queue struct {
+	sender  Sender
+	cfg     QueueConfig
+	msgs    chan queued
+	mu      sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+}
+
+// NewQueue constructs a Queue sending through the sender. Close must be
+// called to send the queued messages and stop the goroutine.
+func NewQueue(sender Sender, cfg QueueConfig) *Queue {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	q := Queue{
+		sender:  sender,
+		cfg:     cfg,
+		msgs:    make(chan queued, cfg.Size),
+		stopped: make(chan struct{}),
+	}
+
+	go q.run()
+
+	return &q
+}
+
+// Send queues the message and returns right away. The context is only used
+// for its values since the message is sent after the call returns.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.msgs <- queued{ctx: context.WithoutCancel(ctx), msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for the queued ones to be sent
+// or the context to be done.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.msgs)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sending queued mail: %d messages pending: %w", len(q.msgs), ctx.Err())
+	}
+}
+
+// run sends the queued messages until the queue is closed.
+func (q *Queue) run() {
+	defer close(q.stopped)
+
+	for m := range q.msgs {
+		ctx, cancel := context.WithTimeout(m.ctx, q.cfg.Timeout)
+		err := q.sender.Send(ctx, m.msg)
+		cancel()
+
+		if err != nil && q.cfg.OnError != nil {
+			q.cfg.OnError(m.msg, err)
+		}
+	}
+}
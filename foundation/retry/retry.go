@@ -0,0 +1,105 @@
+// Package retry provides support for retrying operations with a jittered
+// exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Config describes how an operation is retried. Zero values use the
+// defaults.
+type Config struct {
+	// MaxAttempts is the number of times the operation is tried. Zero means
+	// it's tried until the context is done.
+	MaxAttempts int
+
+	// Initial is the wait after the first failure, 100ms by default.
+	Initial time.Duration
+
+	// Max caps the wait between attempts, 10s by default.
+	Max time.Duration
+
+	// Multiplier grows the wait after every failure, 2 by default.
+	Multiplier float64
+
+	// OnRetry is called after a failed attempt that will be retried, with
+	// the wait before the next attempt. It's optional.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// stopError marks an error that must not be retried.
+type stopError struct {
+	err error
+}
+
+func (se *stopError) Error() string {
+	return se.err.Error()
+}
+
+func (se *stopError) Unwrap() error {
+	return se.err
+}
+
+// Stop wraps an error so Do returns it right away instead of retrying.
+func Stop(err error) error {
+	return &stopError{err: err}
+}
+
+// Do calls fn until it succeeds, the attempts run out, the error is wrapped
+// by Stop or the context is done. The wait between attempts grows
+// exponentially with jitter so many callers failing at once don't retry in
+// lockstep. When giving up, the last error from fn is returned wrapped
+// together with the context error if the context was done.
+func Do(ctx context.Context, cfg Config, fn func(ctx context.Context) error) error {
+	if cfg.Initial <= 0 {
+		cfg.Initial = 100 * time.Millisecond
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 10 * time.Second
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2
+	}
+
+	backoff := cfg.Initial
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var se *stopError
+		if errors.As(err, &se) {
+			return se.err
+		}
+
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			return fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+
+		// Wait between half and all of the backoff.
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt, err, wait)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-t.C:
+		}
+
+		backoff = time.Duration(float64(backoff) * cfg.Multiplier)
+		if backoff > cfg.Max {
+			backoff = cfg.Max
+		}
+	}
+}
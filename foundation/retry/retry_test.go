@@ -0,0 +1,117 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/islamghany/service/foundation/retry"
+)
+
+var errFail = errors.New("fail")
+
+func TestDo(t *testing.T) {
+	tt := []struct {
+		name     string
+		cfg      retry.Config
+		failures int
+		stop     bool
+		attempts int
+		err      bool
+	}{
+		{name: "first try", cfg: retry.Config{MaxAttempts: 3}, failures: 0, attempts: 1},
+		{name: "succeeds on retry", cfg: retry.Config{MaxAttempts: 3}, failures: 2, attempts: 3},
+		{name: "attempts run out", cfg: retry.Config{MaxAttempts: 3}, failures: 5, attempts: 3, err: true},
+		{name: "stopped", cfg: retry.Config{MaxAttempts: 3}, failures: 5, stop: true, attempts: 1, err: true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			tst.cfg.Initial = time.Millisecond
+
+			var attempts int
+			err := retry.Do(context.Background(), tst.cfg, func(ctx context.Context) error {
+				attempts++
+				if attempts > tst.failures {
+					return nil
+				}
+				if tst.stop {
+					return retry.Stop(errFail)
+				}
+				return errFail
+			})
+
+			if attempts != tst.attempts {
+				t.Errorf("got %d attempts, exp %d", attempts, tst.attempts)
+			}
+
+			if tst.err != (err != nil) {
+				t.Fatalf("got error %v, exp error %v", err, tst.err)
+			}
+
+			if tst.err && !errors.Is(err, errFail) {
+				t.Errorf("got %v, exp it to wrap %v", err, errFail)
+			}
+
+			if tst.stop && err != errFail {
+				t.Errorf("got %v, exp the unwrapped %v", err, errFail)
+			}
+		})
+	}
+}
+
+func TestDoBackoff(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 6,
+		Initial:     10 * time.Millisecond,
+		Max:         40 * time.Millisecond,
+		Multiplier:  2,
+	}
+
+	var waits []time.Duration
+	cfg.OnRetry = func(attempt int, err error, wait time.Duration) {
+		waits = append(waits, wait)
+	}
+
+	retry.Do(context.Background(), cfg, func(ctx context.Context) error {
+		return errFail
+	})
+
+	backoffs := []time.Duration{10, 20, 40, 40, 40}
+	if len(waits) != len(backoffs) {
+		t.Fatalf("got %d waits, exp %d", len(waits), len(backoffs))
+	}
+
+	for i, backoff := range backoffs {
+		backoff *= time.Millisecond
+		if waits[i] < backoff/2 || waits[i] > backoff {
+			t.Errorf("wait %d: got %s, exp between %s and %s", i, waits[i], backoff/2, backoff)
+		}
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cfg := retry.Config{
+		Initial: time.Hour,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			cancel()
+		},
+	}
+
+	var attempts int
+	err := retry.Do(ctx, cfg, func(ctx context.Context) error {
+		attempts++
+		return errFail
+	})
+
+	if attempts != 1 {
+		t.Errorf("got %d attempts, exp 1", attempts)
+	}
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errFail) {
+		t.Errorf("got %v, exp it to wrap %v and %v", err, context.Canceled, errFail)
+	}
+}
@@ -0,0 +1,81 @@
+// Package throttle provides support for limiting how often an action is
+// taken per key, such as a client IP address.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows a number of attempts per key within a fixed window. The
+// state is kept in memory, so every instance of a service limits on its
+// own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*window
+	swept   time.Time
+}
+
+// window counts the attempts of a key since it started.
+type window struct {
+	start    time.Time
+	attempts int
+}
+
+// New constructs a Limiter allowing limit attempts per key within the
+// window. A limit of zero or less allows every attempt.
+func New(limit int, every time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  every,
+		windows: make(map[string]*window),
+		swept:   time.Now(),
+	}
+}
+
+// Allow records an attempt for the key and reports whether it's within the
+// limit. When it's not, the time until the key can try again is returned.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, exists := l.windows[key]
+	if !exists || now.Sub(w.start) >= l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	w.attempts++
+	if w.attempts > l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+
+	return true, 0
+}
+
+// sweep drops the windows that ended, at most once per window, so keys
+// that stopped trying don't hold memory.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+
+	l.swept = now
+}
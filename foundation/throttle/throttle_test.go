@@ -0,0 +1,96 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/islamghany/service/foundation/throttle"
+)
+
+func TestAllow(t *testing.T) {
+	type attempt struct {
+		key   string
+		sleep time.Duration
+		ok    bool
+	}
+
+	tt := []struct {
+		name     string
+		limit    int
+		window   time.Duration
+		attempts []attempt
+	}{
+		{
+			name:   "within limit",
+			limit:  2,
+			window: time.Minute,
+			attempts: []attempt{
+				{key: "a", ok: true},
+				{key: "a", ok: true},
+			},
+		},
+		{
+			name:   "over limit",
+			limit:  2,
+			window: time.Minute,
+			attempts: []attempt{
+				{key: "a", ok: true},
+				{key: "a", ok: true},
+				{key: "a", ok: false},
+				{key: "a", ok: false},
+			},
+		},
+		{
+			name:   "keys limited apart",
+			limit:  1,
+			window: time.Minute,
+			attempts: []attempt{
+				{key: "a", ok: true},
+				{key: "b", ok: true},
+				{key: "a", ok: false},
+				{key: "b", ok: false},
+			},
+		},
+		{
+			name:   "window ends",
+			limit:  1,
+			window: 20 * time.Millisecond,
+			attempts: []attempt{
+				{key: "a", ok: true},
+				{key: "a", ok: false},
+				{key: "a", sleep: 30 * time.Millisecond, ok: true},
+			},
+		},
+		{
+			name:   "no limit",
+			limit:  0,
+			window: time.Minute,
+			attempts: []attempt{
+				{key: "a", ok: true},
+				{key: "a", ok: true},
+			},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			l := throttle.New(tst.limit, tst.window)
+
+			for i, a := range tst.attempts {
+				time.Sleep(a.sleep)
+
+				ok, retryAfter := l.Allow(a.key)
+				if ok != a.ok {
+					t.Fatalf("attempt %d: got %v, exp %v", i, ok, a.ok)
+				}
+
+				switch {
+				case ok && retryAfter != 0:
+					t.Errorf("attempt %d: got retry after %s, exp 0", i, retryAfter)
+				case !ok && (retryAfter <= 0 || retryAfter > tst.window):
+					t.Errorf("attempt %d: got retry after %s, exp within %s", i, retryAfter, tst.window)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package web
 import (
 	"context"
 	"time"
+
+	"github.com/dimfeld/httptreemux/v5"
 )
 
 type ctxKey int
@@ -61,3 +63,9 @@ func SetStatusCode(ctx context.Context, statusCode int) {
 
 	v.StatusCode = statusCode
 }
+
+// GetRoute returns the route pattern that matched the request, such as
+// "/v1/users/:user_id".
+func GetRoute(ctx context.Context) string {
+	return httptreemux.ContextRoute(ctx)
+}